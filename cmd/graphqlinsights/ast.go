@@ -0,0 +1,55 @@
+// Package main provides the entry point for the GraphQL Insights application
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/tom/graphqlinsights/pkg/parser"
+)
+
+// runAST parses the file named in args and writes its graphql-js style AST to
+// stdout as JSON, indented when --pretty is given before the file name. Errors
+// are written to stderr. It returns the process exit code: 0 on success, 1 when
+// the file cannot be read or parsed, and 2 for a usage error.
+func runAST(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("ast", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	pretty := flags.Bool("pretty", false, "indent the JSON output")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: graphqlinsights ast [--pretty] <file.graphql>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	file := flags.Arg(0)
+	input, err := os.ReadFile(file)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %s\n", file, err)
+		return 1
+	}
+	document, err := parser.NewParser(string(input)).ParseDocument()
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %s\n", file, err)
+		return 1
+	}
+
+	encoder := json.NewEncoder(stdout)
+	if *pretty {
+		encoder.SetIndent("", "  ")
+	}
+	if err := encoder.Encode(document); err != nil {
+		fmt.Fprintf(stderr, "%s: %s\n", file, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,83 @@
+// Package main provides the entry point for the GraphQL Insights application
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunAST(t *testing.T) {
+	log.Println("Starting TestRunAST")
+	sample := filepath.Join("testdata", "sample.graphql")
+	tests := []struct {
+		name       string
+		args       []string
+		wantPretty bool
+	}{
+		{name: "Compact", args: []string{sample}},
+		{name: "Pretty", args: []string{"--pretty", sample}, wantPretty: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := runAST(tt.args, &stdout, &stderr); code != 0 {
+				t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+			}
+			if stderr.Len() != 0 {
+				t.Errorf("Expected nothing on stderr, got %s", stderr.String())
+			}
+
+			var ast struct {
+				Kind        string `json:"kind"`
+				Definitions []struct {
+					Kind string `json:"kind"`
+				} `json:"definitions"`
+			}
+			if err := json.Unmarshal(stdout.Bytes(), &ast); err != nil {
+				t.Fatalf("Expected valid JSON, got %s: %s", stdout.String(), err)
+			}
+			if ast.Kind != "Document" || len(ast.Definitions) != 2 ||
+				ast.Definitions[0].Kind != "OperationDefinition" || ast.Definitions[1].Kind != "FragmentDefinition" {
+				t.Errorf("Unexpected AST %s", stdout.String())
+			}
+			if pretty := strings.Contains(stdout.String(), "\n  "); pretty != tt.wantPretty {
+				t.Errorf("Expected indented output %t, got %s", tt.wantPretty, stdout.String())
+			}
+		})
+	}
+}
+
+func TestRunASTErrors(t *testing.T) {
+	log.Println("Starting TestRunASTErrors")
+	tests := []struct {
+		name      string
+		args      []string
+		wantCode  int
+		wantError string
+	}{
+		{name: "Parse error", args: []string{filepath.Join("testdata", "invalid.graphql")}, wantCode: 1, wantError: "at line 4, column 3"},
+		{name: "Missing file", args: []string{filepath.Join("testdata", "missing.graphql")}, wantCode: 1, wantError: "no such file or directory"},
+		{name: "No file", wantCode: 2, wantError: "usage: graphqlinsights ast"},
+		{name: "Unknown flag", args: []string{"--compact", "query.graphql"}, wantCode: 2, wantError: "flag provided but not defined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := runAST(tt.args, &stdout, &stderr); code != tt.wantCode {
+				t.Errorf("Expected exit code %d, got %d", tt.wantCode, code)
+			}
+			if stdout.Len() != 0 {
+				t.Errorf("Expected nothing on stdout, got %s", stdout.String())
+			}
+			if !strings.Contains(stderr.String(), tt.wantError) {
+				t.Errorf("Expected stderr to contain %q, got %s", tt.wantError, stderr.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+// Package main provides the entry point for the GraphQL Insights application
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/tom/graphqlinsights/pkg/analytics"
+)
+
+// Environment variables read by LoadConfig
+const (
+	envWorkers       = "GQLINSIGHTS_WORKERS"
+	envQueueSize     = "GQLINSIGHTS_QUEUE"
+	envNameAnonymous = "GQLINSIGHTS_NAME_ANONYMOUS"
+	envWebhookURL    = "GQLINSIGHTS_WEBHOOK_URL"
+	envAlertDepth    = "GQLINSIGHTS_ALERT_MAX_DEPTH"
+	envAlertCost     = "GQLINSIGHTS_ALERT_MAX_COMPLEXITY"
+	envSampleRate    = "GQLINSIGHTS_SAMPLE_RATE"
+	envKeepDistinct  = "GQLINSIGHTS_KEEP_DISTINCT_QUERIES"
+	envRateLimit     = "GQLINSIGHTS_RATE_LIMIT"
+	envRateBurst     = "GQLINSIGHTS_RATE_BURST"
+	envDedupWindow   = "GQLINSIGHTS_DEDUP_WINDOW"
+)
+
+// Defaults used when an environment variable is unset
+const (
+	defaultWorkers     = 5
+	defaultQueueSize   = 100
+	defaultRateLimit   = 50
+	defaultRateBurst   = 100
+	defaultDedupWindow = time.Minute
+)
+
+// Config holds the server settings operators can tune without recompiling
+type Config struct {
+	Workers   int // Number of workers processing queued events
+	QueueSize int // Number of events buffered before the analytics endpoint returns 503
+
+	// RateLimit is the number of events per second each client may send, with
+	// bursts of up to RateBurst events; zero disables rate limiting
+	RateLimit float64
+	RateBurst int
+
+	// DedupWindow collapses identical operations from the same client seen within
+	// the window; zero disables deduplication
+	DedupWindow time.Duration
+
+	// NameAnonymousOperations reports anonymous operations under a name derived
+	// from their fingerprint rather than all under one shared name
+	NameAnonymousOperations bool
+
+	// WebhookURL receives an alert for every operation over AlertThresholds;
+	// empty disables alerts. An unset threshold disables that check.
+	WebhookURL      string
+	AlertThresholds analytics.Thresholds
+
+	// SampleRate is the fraction of events, between 0 and 1, that are processed;
+	// zero or one disables sampling. KeepDistinctQueries always processes the
+	// first event of each distinct query.
+	SampleRate          float64
+	KeepDistinctQueries bool
+}
+
+// LoadConfig reads the configuration using getenv, typically os.Getenv, falling
+// back to defaults for unset variables. It returns an error naming the variable
+// if a value is not valid.
+func LoadConfig(getenv func(string) string) (Config, error) {
+	workers, err := positiveIntEnv(getenv, envWorkers, defaultWorkers)
+	if err != nil {
+		return Config{}, err
+	}
+	queueSize, err := positiveIntEnv(getenv, envQueueSize, defaultQueueSize)
+	if err != nil {
+		return Config{}, err
+	}
+	rateLimit, err := nonNegativeFloatEnv(getenv, envRateLimit, defaultRateLimit)
+	if err != nil {
+		return Config{}, err
+	}
+	rateBurst, err := positiveIntEnv(getenv, envRateBurst, defaultRateBurst)
+	if err != nil {
+		return Config{}, err
+	}
+	dedupWindow, err := durationEnv(getenv, envDedupWindow, defaultDedupWindow)
+	if err != nil {
+		return Config{}, err
+	}
+	nameAnonymous, err := boolEnv(getenv, envNameAnonymous)
+	if err != nil {
+		return Config{}, err
+	}
+	maxDepth, err := positiveIntEnv(getenv, envAlertDepth, 0)
+	if err != nil {
+		return Config{}, err
+	}
+	maxComplexity, err := positiveIntEnv(getenv, envAlertCost, 0)
+	if err != nil {
+		return Config{}, err
+	}
+	sampleRate, err := fractionEnv(getenv, envSampleRate)
+	if err != nil {
+		return Config{}, err
+	}
+	keepDistinct, err := boolEnv(getenv, envKeepDistinct)
+	if err != nil {
+		return Config{}, err
+	}
+	return Config{
+		Workers:                 workers,
+		QueueSize:               queueSize,
+		RateLimit:               rateLimit,
+		RateBurst:               rateBurst,
+		DedupWindow:             dedupWindow,
+		NameAnonymousOperations: nameAnonymous,
+		WebhookURL:              getenv(envWebhookURL),
+		AlertThresholds:         analytics.Thresholds{MaxDepth: maxDepth, MaxComplexity: maxComplexity},
+		SampleRate:              sampleRate,
+		KeepDistinctQueries:     keepDistinct,
+	}, nil
+}
+
+// positiveIntEnv parses the named variable as a positive integer, returning fallback if it is unset
+func positiveIntEnv(getenv func(string) string, name string, fallback int) (int, error) {
+	raw := getenv(name)
+	if raw == "" {
+		return fallback, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 1 {
+		return 0, fmt.Errorf("%s must be a positive integer, got %q", name, raw)
+	}
+	return value, nil
+}
+
+// nonNegativeFloatEnv parses the named variable as a number of zero or more, returning fallback if it is unset
+func nonNegativeFloatEnv(getenv func(string) string, name string, fallback float64) (float64, error) {
+	raw := getenv(name)
+	if raw == "" {
+		return fallback, nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || !(value >= 0) || value > math.MaxFloat64 {
+		return 0, fmt.Errorf("%s must be a non-negative number, got %q", name, raw)
+	}
+	return value, nil
+}
+
+// durationEnv parses the named variable as a duration of zero or more such as 30s or 5m, returning fallback if it is unset
+func durationEnv(getenv func(string) string, name string, fallback time.Duration) (time.Duration, error) {
+	raw := getenv(name)
+	if raw == "" {
+		return fallback, nil
+	}
+	value, err := time.ParseDuration(raw)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative duration such as 30s, got %q", name, raw)
+	}
+	return value, nil
+}
+
+// fractionEnv parses the named variable as a number between 0 and 1, returning 0 if it is unset
+func fractionEnv(getenv func(string) string, name string) (float64, error) {
+	raw := getenv(name)
+	if raw == "" {
+		return 0, nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || !(value >= 0 && value <= 1) {
+		return 0, fmt.Errorf("%s must be a number between 0 and 1, got %q", name, raw)
+	}
+	return value, nil
+}
+
+// boolEnv parses the named variable as a boolean such as true or 0, returning false if it is unset
+func boolEnv(getenv func(string) string, name string) (bool, error) {
+	raw := getenv(name)
+	if raw == "" {
+		return false, nil
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("%s must be a boolean, got %q", name, raw)
+	}
+	return value, nil
+}
@@ -0,0 +1,146 @@
+// Package main provides the entry point for the GraphQL Insights application
+package main
+
+import (
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tom/graphqlinsights/pkg/analytics"
+)
+
+func TestLoadConfig(t *testing.T) {
+	log.Println("Starting TestLoadConfig")
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    Config
+		wantErr string
+	}{
+		{
+			name: "Defaults",
+			env:  map[string]string{},
+			want: Config{Workers: 5, QueueSize: 100, RateLimit: 50, RateBurst: 100, DedupWindow: time.Minute},
+		},
+		{
+			name: "Overrides",
+			env:  map[string]string{"GQLINSIGHTS_WORKERS": "12", "GQLINSIGHTS_QUEUE": "5000"},
+			want: Config{Workers: 12, QueueSize: 5000, RateLimit: 50, RateBurst: 100, DedupWindow: time.Minute},
+		},
+		{
+			name: "Rate limit and dedup window",
+			env: map[string]string{
+				"GQLINSIGHTS_RATE_LIMIT":   "2.5",
+				"GQLINSIGHTS_RATE_BURST":   "10",
+				"GQLINSIGHTS_DEDUP_WINDOW": "30s",
+			},
+			want: Config{Workers: 5, QueueSize: 100, RateLimit: 2.5, RateBurst: 10, DedupWindow: 30 * time.Second},
+		},
+		{
+			name: "Rate limit and dedup window disabled",
+			env:  map[string]string{"GQLINSIGHTS_RATE_LIMIT": "0", "GQLINSIGHTS_DEDUP_WINDOW": "0"},
+			want: Config{Workers: 5, QueueSize: 100, RateBurst: 100},
+		},
+		{
+			name:    "Negative rate limit",
+			env:     map[string]string{"GQLINSIGHTS_RATE_LIMIT": "-1"},
+			wantErr: `GQLINSIGHTS_RATE_LIMIT must be a non-negative number, got "-1"`,
+		},
+		{
+			name:    "Zero rate burst",
+			env:     map[string]string{"GQLINSIGHTS_RATE_BURST": "0"},
+			wantErr: `GQLINSIGHTS_RATE_BURST must be a positive integer, got "0"`,
+		},
+		{
+			name:    "Dedup window without a unit",
+			env:     map[string]string{"GQLINSIGHTS_DEDUP_WINDOW": "60"},
+			wantErr: `GQLINSIGHTS_DEDUP_WINDOW must be a non-negative duration such as 30s, got "60"`,
+		},
+		{
+			name: "Named anonymous operations",
+			env:  map[string]string{"GQLINSIGHTS_NAME_ANONYMOUS": "true"},
+			want: Config{Workers: 5, QueueSize: 100, RateLimit: 50, RateBurst: 100, DedupWindow: time.Minute, NameAnonymousOperations: true},
+		},
+		{
+			name: "Alerts",
+			env: map[string]string{
+				"GQLINSIGHTS_WEBHOOK_URL":          "https://hooks.example.com/alerts",
+				"GQLINSIGHTS_ALERT_MAX_DEPTH":      "8",
+				"GQLINSIGHTS_ALERT_MAX_COMPLEXITY": "1000",
+			},
+			want: Config{
+				Workers:         5,
+				QueueSize:       100,
+				RateLimit:       50,
+				RateBurst:       100,
+				DedupWindow:     time.Minute,
+				WebhookURL:      "https://hooks.example.com/alerts",
+				AlertThresholds: analytics.Thresholds{MaxDepth: 8, MaxComplexity: 1000},
+			},
+		},
+		{
+			name: "Sampling",
+			env:  map[string]string{"GQLINSIGHTS_SAMPLE_RATE": "0.25", "GQLINSIGHTS_KEEP_DISTINCT_QUERIES": "1"},
+			want: Config{Workers: 5, QueueSize: 100, RateLimit: 50, RateBurst: 100, DedupWindow: time.Minute, SampleRate: 0.25, KeepDistinctQueries: true},
+		},
+		{
+			name:    "Sample rate above one",
+			env:     map[string]string{"GQLINSIGHTS_SAMPLE_RATE": "1.5"},
+			wantErr: `GQLINSIGHTS_SAMPLE_RATE must be a number between 0 and 1, got "1.5"`,
+		},
+		{
+			name:    "Negative sample rate",
+			env:     map[string]string{"GQLINSIGHTS_SAMPLE_RATE": "-0.1"},
+			wantErr: `GQLINSIGHTS_SAMPLE_RATE must be a number between 0 and 1, got "-0.1"`,
+		},
+		{
+			name:    "Non-numeric sample rate",
+			env:     map[string]string{"GQLINSIGHTS_SAMPLE_RATE": "NaN"},
+			wantErr: `GQLINSIGHTS_SAMPLE_RATE must be a number between 0 and 1, got "NaN"`,
+		},
+		{
+			name:    "Zero alert depth",
+			env:     map[string]string{"GQLINSIGHTS_ALERT_MAX_DEPTH": "0"},
+			wantErr: `GQLINSIGHTS_ALERT_MAX_DEPTH must be a positive integer, got "0"`,
+		},
+		{
+			name:    "Invalid boolean",
+			env:     map[string]string{"GQLINSIGHTS_NAME_ANONYMOUS": "sometimes"},
+			wantErr: `GQLINSIGHTS_NAME_ANONYMOUS must be a boolean, got "sometimes"`,
+		},
+		{
+			name:    "Non-numeric workers",
+			env:     map[string]string{"GQLINSIGHTS_WORKERS": "many"},
+			wantErr: `GQLINSIGHTS_WORKERS must be a positive integer, got "many"`,
+		},
+		{
+			name:    "Zero queue size",
+			env:     map[string]string{"GQLINSIGHTS_QUEUE": "0"},
+			wantErr: `GQLINSIGHTS_QUEUE must be a positive integer, got "0"`,
+		},
+		{
+			name:    "Negative workers",
+			env:     map[string]string{"GQLINSIGHTS_WORKERS": "-3"},
+			wantErr: "GQLINSIGHTS_WORKERS",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadConfig(func(name string) string { return tt.env[name] })
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
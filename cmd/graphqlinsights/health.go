@@ -0,0 +1,59 @@
+// Package main provides the entry point for the GraphQL Insights application
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// HealthResponse is the JSON body returned by the health and readiness endpoints
+type HealthResponse struct {
+	Status string `json:"status"`
+}
+
+// setReady records whether the server should receive traffic
+func (s *Server) setReady(ready bool) {
+	s.mu.Lock()
+	s.ready = ready
+	s.mu.Unlock()
+}
+
+// Ready reports whether workers are running and the server is not shutting down
+func (s *Server) Ready() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ready
+}
+
+// handleHealth reports that the process is alive and serving requests
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, r, true, "ok")
+}
+
+// handleReady reports whether the server should receive traffic: not before its
+// workers are started and not once graceful shutdown has begun
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if s.Ready() {
+		writeHealth(w, r, true, "ready")
+	} else {
+		writeHealth(w, r, false, "not ready")
+	}
+}
+
+// writeHealth writes a health response with status 200 when healthy and 503 otherwise
+func writeHealth(w http.ResponseWriter, r *http.Request, healthy bool, status string) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(HealthResponse{Status: status}); err != nil {
+		log.Printf("Could not write health response: %s", err)
+	}
+}
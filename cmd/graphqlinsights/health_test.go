@@ -0,0 +1,51 @@
+// Package main provides the entry point for the GraphQL Insights application
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// checkHealth requests a health endpoint from handler and checks its status code and body
+func checkHealth(t *testing.T, handler http.Handler, path string, wantCode int, wantStatus string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	if rec.Code != wantCode {
+		t.Errorf("%s: expected status %d, got %d", path, wantCode, rec.Code)
+	}
+	var body HealthResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("%s: could not decode body: %s", path, err)
+	}
+	if body.Status != wantStatus {
+		t.Errorf("%s: expected status %q, got %q", path, wantStatus, body.Status)
+	}
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	log.Println("Starting TestHealthEndpoints")
+	server := NewServer(10)
+	handler := server.Handler()
+
+	// Before the workers start the process is alive but should not get traffic
+	checkHealth(t, handler, "/healthz", http.StatusOK, "ok")
+	checkHealth(t, handler, "/readyz", http.StatusServiceUnavailable, "not ready")
+
+	server.Start(2)
+	checkHealth(t, handler, "/healthz", http.StatusOK, "ok")
+	checkHealth(t, handler, "/readyz", http.StatusOK, "ready")
+
+	_, _, stop := serve(t, server)
+	stop()
+	checkHealth(t, handler, "/readyz", http.StatusServiceUnavailable, "not ready")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/healthz", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for POST, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,101 @@
+// Package main provides the entry point for the GraphQL Insights application
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/tom/graphqlinsights/pkg/parser"
+)
+
+// lintMaxDepth is the deepest selection nesting lint accepts without a warning
+const lintMaxDepth = 10
+
+// Severities of lint diagnostics
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Diagnostic is a single problem found while linting a query. Line and Column are
+// zero when the problem has no single source position.
+type Diagnostic struct {
+	Severity string
+	Line     int
+	Column   int
+	Message  string
+}
+
+// LintQuery parses a GraphQL document and reports parse errors and unresolvable
+// fragment spreads as errors, and excessive depth, duplicate field selections,
+// unused variables, and subscriptions without exactly one root field as warnings
+func LintQuery(input string) []Diagnostic {
+	document, err := parser.NewParser(input).ParseDocument()
+	if err != nil {
+		if parseErr, ok := err.(*parser.ParseError); ok {
+			return []Diagnostic{{Severity: SeverityError, Line: parseErr.Line, Column: parseErr.Column, Message: parseErr.Message}}
+		}
+		return []Diagnostic{{Severity: SeverityError, Message: err.Error()}}
+	}
+	inlined, err := parser.InlineFragments(document)
+	if err == nil {
+		// Inlining only follows fragments that operations use
+		err = parser.ValidateFragments(document)
+	}
+	if err != nil {
+		return []Diagnostic{{Severity: SeverityError, Message: err.Error()}}
+	}
+
+	var diagnostics []Diagnostic
+	warn := func(format string, args ...any) {
+		diagnostics = append(diagnostics, Diagnostic{Severity: SeverityWarning, Message: fmt.Sprintf(format, args...)})
+	}
+	if depth := document.Depth(); depth > lintMaxDepth {
+		warn("selection depth %d exceeds %d", depth, lintMaxDepth)
+	}
+	for _, path := range parser.FindDuplicateFields(document) {
+		warn("field %s is selected more than once", path)
+	}
+	for _, operation := range inlined.Operations() {
+		for _, name := range operation.UnusedVariables() {
+			warn("variable $%s of %s is never used", name, operationName(operation))
+		}
+		// Fragments are inlined, so every root field is visible here
+		if rootFields := parser.FieldsAtDepth(operation, 1); operation.Type == parser.NodeSubscription && len(rootFields) != 1 {
+			warn("subscription %s selects %d root fields; subscriptions must select exactly one", operationName(operation), len(rootFields))
+		}
+	}
+	return diagnostics
+}
+
+// runLint lints each named file, printing diagnostics to out in the form
+// file:line:column: severity: message. It returns the process exit code: 0 when
+// no file has errors, 1 when any does, and 2 for a usage error.
+func runLint(files []string, out io.Writer) int {
+	if len(files) == 0 {
+		fmt.Fprintln(out, "usage: graphqlinsights lint <file.graphql>...")
+		return 2
+	}
+
+	code := 0
+	for _, file := range files {
+		input, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(out, "%s: %s: %s\n", file, SeverityError, err)
+			code = 1
+			continue
+		}
+		for _, d := range LintQuery(string(input)) {
+			if d.Line > 0 {
+				fmt.Fprintf(out, "%s:%d:%d: %s: %s\n", file, d.Line, d.Column, d.Severity, d.Message)
+			} else {
+				fmt.Fprintf(out, "%s: %s: %s\n", file, d.Severity, d.Message)
+			}
+			if d.Severity == SeverityError {
+				code = 1
+			}
+		}
+	}
+	return code
+}
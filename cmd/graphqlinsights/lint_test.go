@@ -0,0 +1,115 @@
+// Package main provides the entry point for the GraphQL Insights application
+package main
+
+import (
+	"bytes"
+	"log"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLintQuery(t *testing.T) {
+	log.Println("Starting TestLintQuery")
+	tests := []struct {
+		name  string
+		input string
+		want  []Diagnostic
+	}{
+		{
+			name:  "Clean query",
+			input: `query GetUser($id: ID!) { user(id: $id) { name } }`,
+			want:  nil,
+		},
+		{
+			name:  "Parse error",
+			input: "query {\n  user {\n}",
+			want:  []Diagnostic{{Severity: SeverityError, Line: 3, Column: 2, Message: "Unexpected token: expected } but got EOF"}},
+		},
+		{
+			name:  "Unknown fragment",
+			input: `{ user { ...Missing } }`,
+			want:  []Diagnostic{{Severity: SeverityError, Message: "unknown fragment Missing"}},
+		},
+		{
+			name:  "Cycle in unused fragment",
+			input: "{ user { id } }\nfragment A on User { ...A }",
+			want:  []Diagnostic{{Severity: SeverityError, Message: "cyclic fragment: A -> A"}},
+		},
+		{
+			name:  "Subscription",
+			input: `subscription OnMessage($room: ID!) @live { messageAdded(room: $room) { id text } }`,
+			want:  nil,
+		},
+		{
+			name:  "Subscription with several root fields",
+			input: "subscription OnMessage { messageAdded { id } ...Typing }\nfragment Typing on Subscription { userTyping { name } }",
+			want:  []Diagnostic{{Severity: SeverityWarning, Message: "subscription OnMessage selects 2 root fields; subscriptions must select exactly one"}},
+		},
+		{
+			name:  "Too deep",
+			input: `{ a { a { a { a { a { a { a { a { a { a { a } } } } } } } } } } }`,
+			want:  []Diagnostic{{Severity: SeverityWarning, Message: "selection depth 11 exceeds 10"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LintQuery(tt.input); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRunLint(t *testing.T) {
+	log.Println("Starting TestRunLint")
+	tests := []struct {
+		name     string
+		files    []string
+		wantCode int
+		wantOut  []string
+	}{
+		{
+			name:     "Parse error",
+			files:    []string{filepath.Join("testdata", "invalid.graphql")},
+			wantCode: 1,
+			wantOut: []string{
+				filepath.Join("testdata", "invalid.graphql") + `:4:3: error: Unexpected token in selection set: expected a field, fragment, or } but got ) ")"`,
+			},
+		},
+		{
+			name:     "Warnings only",
+			files:    []string{filepath.Join("testdata", "warnings.graphql")},
+			wantCode: 0,
+			wantOut: []string{
+				filepath.Join("testdata", "warnings.graphql") + ": warning: field posts.title is selected more than once",
+				filepath.Join("testdata", "warnings.graphql") + ": warning: variable $unused of Feed is never used",
+			},
+		},
+		{
+			name:     "Missing file",
+			files:    []string{filepath.Join("testdata", "missing.graphql")},
+			wantCode: 1,
+			wantOut:  []string{filepath.Join("testdata", "missing.graphql") + ": error: open " + filepath.Join("testdata", "missing.graphql") + ": no such file or directory"},
+		},
+		{
+			name:     "No files",
+			wantCode: 2,
+			wantOut:  []string{"usage: graphqlinsights lint <file.graphql>..."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if code := runLint(tt.files, &out); code != tt.wantCode {
+				t.Errorf("Expected exit code %d, got %d", tt.wantCode, code)
+			}
+			if got := strings.Split(strings.TrimSpace(out.String()), "\n"); !reflect.DeepEqual(got, tt.wantOut) {
+				t.Errorf("Expected output %q, got %q", tt.wantOut, got)
+			}
+		})
+	}
+}
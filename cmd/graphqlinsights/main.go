@@ -2,15 +2,16 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"log"
-	"net/http"
+	"net"
 	"os"
-	"regexp"
-	"strings"
-	"sync"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/tom/graphqlinsights/pkg/analytics"
 	"github.com/tom/graphqlinsights/pkg/lexer"
 	"github.com/tom/graphqlinsights/pkg/parser"
 )
@@ -44,56 +45,23 @@ const exampleQuery = `query GetUser($id: ID!) {
   }
 }`
 
-var (
-	eventQueue = make(chan AnalyticsData, 100) // Buffered channel for events
-	wg         sync.WaitGroup
-)
-
-// ParseGraphQLQuery parses a GraphQL query string into a GraphQLQuery data structure
+// ParseGraphQLQuery parses a GraphQL query string into a GraphQLQuery data structure,
+// counting every field selected anywhere in the document. Queries that cannot be
+// parsed yield an empty field count.
 func ParseGraphQLQuery(query string) GraphQLQuery {
-	query = strings.TrimSpace(query)
 	fields := make(map[string]int)
-	parseFields(query, fields)
-	return GraphQLQuery{Fields: fields}
-}
-
-// parseFields is a helper function to parse fields from a GraphQL query string
-func parseFields(query string, fields map[string]int) {
-	// Improved parsing logic to count field usage
-	fieldRegex := regexp.MustCompile(`(?m)^\s*(\w+)\s*\(`)
-	matches := fieldRegex.FindAllStringSubmatch(query, -1)
-	for _, match := range matches {
-		if len(match) > 1 {
-			fields[match[1]]++
-		}
-	}
-}
-
-// worker function to process events
-func worker(id int) {
-	defer wg.Done()
-	for event := range eventQueue {
-		log.Printf("Worker %d processing event at %d", id, event.Timestamp)
-		parsedQuery := ParseGraphQLQuery(event.OperationBody)
-		log.Printf("Parsed query: %+v", parsedQuery)
-
-		// Also parse using the proper parser
-		p := parser.NewParser(event.OperationBody)
-		result := p.ParseQuery()
-		log.Printf("Properly parsed query structure:\n%s", result.Print(""))
+	document, err := parser.NewParser(query).ParseDocument()
+	if err != nil {
+		log.Printf("Could not parse query for field counts: %s", err)
+		return GraphQLQuery{Fields: fields}
 	}
-}
-
-// handler function to process incoming analytics data
-func handler(w http.ResponseWriter, r *http.Request) {
-	var data AnalyticsData
-	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-	// Send event to the queue
-	eventQueue <- data
-	fmt.Fprintf(w, "Data received")
+	parser.Walk(document, func(n *parser.Node) bool {
+		if n.Type == parser.NodeField {
+			fields[n.Name]++
+		}
+		return true
+	})
+	return GraphQLQuery{Fields: fields}
 }
 
 // demonstrateLexer shows how the lexer works with an example query
@@ -110,6 +78,16 @@ func demonstrateLexer(input string) {
 }
 
 func main() {
+	// Subcommands work on query files and exit instead of serving
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "lint":
+			os.Exit(runLint(os.Args[2:], os.Stdout))
+		case "ast":
+			os.Exit(runAST(os.Args[2:], os.Stdout, os.Stderr))
+		}
+	}
+
 	// Default example query
 	input := `query GetUser { user(id: "123") { name } }`
 
@@ -122,37 +100,61 @@ func main() {
 
 	// Create a parser with the input and demonstrate normal parser functionality
 	p := parser.NewParser(input)
-	result := p.ParseQuery()
+	result, err := p.ParseDocument()
+	if err != nil {
+		log.Fatalf("Could not parse query: %s", err)
+	}
 	fmt.Println("Parser output:")
 	fmt.Print(result.Print(""))
 
 	// Demonstrate lexer functionality
 	demonstrateLexer(input)
 
-	// Parse using the regex-based parser and log
+	// Count field usage and log
 	parsedQuery := ParseGraphQLQuery(input)
-	log.Printf("Regex-based parsed query: %+v\n", parsedQuery)
+	log.Printf("Field counts for query: %+v\n", parsedQuery)
 
 	// Also parse and log the example query with variables
 	parsedExampleQuery := ParseGraphQLQuery(exampleQuery)
 	log.Printf("Parsed example query with variables: %+v\n", parsedExampleQuery)
 
-	// Start worker pool for analytics processing
-	numWorkers := 5
-	wg.Add(numWorkers)
-	for i := 1; i <= numWorkers; i++ {
-		go worker(i)
+	config, err := LoadConfig(os.Getenv)
+	if err != nil {
+		log.Fatalf("Invalid configuration: %s", err)
 	}
 
-	// Set up HTTP server for analytics data
-	http.HandleFunc("/analytics", handler)
-	log.Println("Server started on :8080")
+	// Alert on operations over the thresholds if a webhook is configured
+	var notifier *analytics.WebhookNotifier
+	if config.WebhookURL != "" {
+		notifier = analytics.NewWebhookNotifier(analytics.WebhookConfig{URL: config.WebhookURL, Retries: 2, RetryDelay: time.Second})
+	}
+
+	// Start worker pool for analytics processing
+	server := NewServerWithOptions(config.QueueSize, ServerOptions{
+		DedupWindow:             config.DedupWindow,
+		RateLimit:               config.RateLimit,
+		RateBurst:               config.RateBurst,
+		SampleRate:              config.SampleRate,
+		KeepDistinctQueries:     config.KeepDistinctQueries,
+		NameAnonymousOperations: config.NameAnonymousOperations,
+		Thresholds:              config.AlertThresholds,
+		Notifier:                notifier,
+		Rollup:                  analytics.NewHourlyRollup(analytics.NewMemoryStore(), analytics.SystemClock{}, time.Hour),
+	})
+	server.Start(config.Workers)
 
-	if err := http.ListenAndServe(":8080", nil); err != nil {
+	// Set up HTTP server for analytics data
+	listener, err := net.Listen("tcp", ":8080")
+	if err != nil {
 		log.Fatalf("Could not start server: %s", err.Error())
 	}
+	log.Println("Server started on :8080")
 
-	// Close the event queue and wait for workers to finish
-	close(eventQueue)
-	wg.Wait()
+	// Serve until interrupted, then drain the workers before exiting
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := server.Serve(ctx, listener); err != nil {
+		log.Fatalf("Server error: %s", err.Error())
+	}
+	log.Println("Server stopped")
 }
@@ -0,0 +1,54 @@
+// Package main provides the entry point for the GraphQL Insights application
+package main
+
+import (
+	"log"
+	"reflect"
+	"testing"
+)
+
+func TestParseGraphQLQuery(t *testing.T) {
+	log.Println("Starting TestParseGraphQLQuery")
+	tests := []struct {
+		name  string
+		input string
+		want  map[string]int
+	}{
+		{
+			// The old regex only matched fields at the start of a line followed by "(",
+			// so this single-line query produced no counts at all
+			name:  "Nested query on one line",
+			input: `query GetUser { user(id: "1") { name friends(first: 2) { name } } }`,
+			want:  map[string]int{"user": 1, "name": 2, "friends": 1},
+		},
+		{
+			name: "Fields without arguments",
+			input: `query GetUser {
+  user(id: "1") {
+    id
+    name
+  }
+}`,
+			want: map[string]int{"user": 1, "id": 1, "name": 1},
+		},
+		{
+			name:  "Fragments",
+			input: `{ viewer { ...Fields } } fragment Fields on User { id name }`,
+			want:  map[string]int{"viewer": 1, "id": 1, "name": 1},
+		},
+		{
+			name:  "Invalid query",
+			input: `query {`,
+			want:  map[string]int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseGraphQLQuery(tt.input)
+			if !reflect.DeepEqual(got.Fields, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got.Fields)
+			}
+		})
+	}
+}
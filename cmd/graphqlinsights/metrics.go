@@ -0,0 +1,90 @@
+// Package main provides the entry point for the GraphQL Insights application
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// metricsPrefix namespaces every exported metric
+const metricsPrefix = "graphqlinsights_"
+
+// depthBuckets are the upper bounds of the query depth histogram buckets
+var depthBuckets = []float64{1, 2, 3, 4, 5, 7, 10, 15, 20}
+
+// histogram counts observations into cumulative buckets as Prometheus expects.
+// It is not safe for concurrent use; the server guards it with its mutex.
+type histogram struct {
+	bounds []float64 // Upper bounds of the buckets in increasing order
+	counts []int     // Observations per bucket; the last one counts values above every bound
+	sum    float64
+	count  int
+}
+
+// newHistogram creates an empty histogram with the given bucket upper bounds
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]int, len(bounds)+1)}
+}
+
+// observe records a single value
+func (h *histogram) observe(value float64) {
+	i := 0
+	for i < len(h.bounds) && value > h.bounds[i] {
+		i++
+	}
+	h.counts[i]++
+	h.sum += value
+	h.count++
+}
+
+// writeTo writes the histogram in the Prometheus text format under name
+func (h *histogram) writeTo(w io.Writer, name, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	cumulative := 0
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket{le=%q} %d\n", name, strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, h.count)
+	fmt.Fprintf(w, "%s_sum %s\n", name, strconv.FormatFloat(h.sum, 'g', -1, 64))
+	fmt.Fprintf(w, "%s_count %d\n", name, h.count)
+}
+
+// writeMetric writes a single counter or gauge in the Prometheus text format
+func writeMetric(w io.Writer, name, kind, help string, value int) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %d\n", name, help, name, kind, name, value)
+}
+
+// handleMetrics reports the server's counters in the Prometheus text exposition format
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Render into a buffer so a slow scraper does not hold up the workers
+	var buf bytes.Buffer
+	s.mu.Lock()
+	writeMetric(&buf, metricsPrefix+"events_received_total", "counter", "Events submitted to the analytics endpoints.", s.received)
+	writeMetric(&buf, metricsPrefix+"events_processed_total", "counter", "Events processed by the workers.", s.processed)
+	writeMetric(&buf, metricsPrefix+"events_dropped_total", "counter", "Events rejected because the queue was full.", s.dropped)
+	writeMetric(&buf, metricsPrefix+"events_rate_limited_total", "counter", "Events rejected because their client exceeded its rate limit.", s.limited)
+	writeMetric(&buf, metricsPrefix+"events_sampled_out_total", "counter", "Events acknowledged but skipped by sampling.", s.sampledOut)
+	writeMetric(&buf, metricsPrefix+"events_deduplicated_total", "counter", "Events collapsed into an earlier identical event.", s.deduped)
+	writeMetric(&buf, metricsPrefix+"query_bytes_total", "counter", "Bytes of operation body processed by the workers.", int(s.fields.TotalQueryBytes()))
+	writeMetric(&buf, metricsPrefix+"parse_errors_total", "counter", "Events whose query could not be parsed.", s.parseErrors)
+	writeMetric(&buf, metricsPrefix+"worker_panics_total", "counter", "Events whose processing panicked and was recovered.", s.panics)
+	writeMetric(&buf, metricsPrefix+"queue_length", "gauge", "Events waiting in the queue.", len(s.queue))
+	s.depths.writeTo(&buf, metricsPrefix+"query_depth", "Selection depth of parsed queries.")
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		log.Printf("Could not write metrics response: %s", err)
+	}
+}
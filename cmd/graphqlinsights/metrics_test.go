@@ -0,0 +1,90 @@
+// Package main provides the entry point for the GraphQL Insights application
+package main
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestHistogram(t *testing.T) {
+	log.Println("Starting TestHistogram")
+	h := newHistogram([]float64{1, 2, 5})
+	for _, value := range []float64{1, 2, 2, 4, 9} {
+		h.observe(value)
+	}
+
+	var buf bytes.Buffer
+	h.writeTo(&buf, "depth", "Query depth.")
+	want := `# HELP depth Query depth.
+# TYPE depth histogram
+depth_bucket{le="1"} 1
+depth_bucket{le="2"} 3
+depth_bucket{le="5"} 4
+depth_bucket{le="+Inf"} 5
+depth_sum 18
+depth_count 5
+`
+	if got := buf.String(); got != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	log.Println("Starting TestMetricsEndpoint")
+	server := NewServerWithOptions(10, ServerOptions{Logger: slog.New(slog.NewJSONHandler(io.Discard, nil))})
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	for _, body := range []string{`{ a }`, `{ a { b } }`, `query {`} {
+		if status := postEvent(t, ts.URL, AnalyticsData{OperationBody: body}); status != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", status)
+		}
+	}
+	// Process the queued events on separate goroutines, as the workers would
+	var wg sync.WaitGroup
+	for i := 1; i <= 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			server.process(i, <-server.queue)
+		}()
+	}
+	wg.Wait()
+
+	resp, err := http.Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("Could not scrape metrics: %s", err)
+	}
+	defer resp.Body.Close()
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain") {
+		t.Errorf("Expected a text/plain response, got %s", contentType)
+	}
+	body, _ := io.ReadAll(resp.Body)
+
+	for _, want := range []string{
+		"# TYPE graphqlinsights_events_received_total counter\ngraphqlinsights_events_received_total 3\n",
+		"graphqlinsights_events_processed_total 3\n",
+		"graphqlinsights_events_dropped_total 0\n",
+		"graphqlinsights_events_rate_limited_total 0\n",
+		"graphqlinsights_events_deduplicated_total 0\n",
+		"graphqlinsights_query_bytes_total 23\n",
+		"graphqlinsights_parse_errors_total 1\n",
+		"graphqlinsights_worker_panics_total 0\n",
+		"# TYPE graphqlinsights_queue_length gauge\ngraphqlinsights_queue_length 0\n",
+		"# TYPE graphqlinsights_query_depth histogram\n",
+		"graphqlinsights_query_depth_bucket{le=\"1\"} 1\n",
+		"graphqlinsights_query_depth_bucket{le=\"2\"} 2\n",
+		"graphqlinsights_query_depth_count 2\n",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("Expected metrics to contain %q, got\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Package main provides the entry point for the GraphQL Insights application
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/tom/graphqlinsights/pkg/analytics"
+)
+
+// tokenBucket holds the tokens currently available to one client
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter is a per-key token bucket rate limiter. Each key may send up to
+// burst events at once, refilled at rate events per second. Buckets that have
+// refilled completely are dropped, so keys that stop sending do not use memory.
+// It is safe for concurrent use by multiple handlers.
+type RateLimiter struct {
+	mu        sync.Mutex
+	clock     analytics.Clock
+	rate      float64
+	burst     float64
+	refill    time.Duration // Time for an empty bucket to refill completely
+	buckets   map[string]*tokenBucket
+	lastPrune time.Time
+}
+
+// NewRateLimiter creates a rate limiter allowing rate events per second per key
+// with bursts of up to burst events
+func NewRateLimiter(clock analytics.Clock, rate float64, burst int) *RateLimiter {
+	return &RateLimiter{
+		clock:     clock,
+		rate:      rate,
+		burst:     float64(burst),
+		refill:    time.Duration(float64(burst) / rate * float64(time.Second)),
+		buckets:   make(map[string]*tokenBucket),
+		lastPrune: clock.Now(),
+	}
+}
+
+// Allow reports whether an event for key may proceed, consuming a token if so
+func (l *RateLimiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.clock.Now()
+	if now.Sub(l.lastPrune) >= l.refill {
+		l.prune(now)
+	}
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens = min(l.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate)
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// prune drops buckets that have refilled to burst since they were last used.
+// This loses nothing, as a key without a bucket starts with a full one.
+func (l *RateLimiter) prune(now time.Time) {
+	for key, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastPrune = now
+}
@@ -0,0 +1,115 @@
+// Package main provides the entry point for the GraphQL Insights application
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock whose time only moves when advanced by the test
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func TestRateLimiterRefills(t *testing.T) {
+	log.Println("Starting TestRateLimiterRefills")
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	limiter := NewRateLimiter(clock, 2, 3)
+
+	for i := 0; i < 3; i++ {
+		if !limiter.Allow("web") {
+			t.Fatalf("Event %d: expected burst to be allowed", i)
+		}
+	}
+	if limiter.Allow("web") {
+		t.Fatalf("Expected event beyond the burst to be rejected")
+	}
+
+	clock.now = clock.now.Add(500 * time.Millisecond)
+	if !limiter.Allow("web") {
+		t.Errorf("Expected one token to be refilled after half a second")
+	}
+	if limiter.Allow("web") {
+		t.Errorf("Expected only one token to be refilled")
+	}
+
+	clock.now = clock.now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		if !limiter.Allow("web") {
+			t.Errorf("Event %d: expected refill to be capped at the burst", i)
+		}
+	}
+	if limiter.Allow("web") {
+		t.Errorf("Expected refill to be capped at the burst")
+	}
+}
+
+func TestRateLimiterPrunesFullBuckets(t *testing.T) {
+	log.Println("Starting TestRateLimiterPrunesFullBuckets")
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	limiter := NewRateLimiter(clock, 2, 4) // Refills completely in two seconds
+
+	for i := 0; i < 1000; i++ {
+		limiter.Allow(fmt.Sprintf("random-%d", i))
+	}
+	for i := 0; i < 4; i++ {
+		limiter.Allow("busy")
+	}
+	if len(limiter.buckets) != 1001 {
+		t.Fatalf("Expected 1001 buckets, got %d", len(limiter.buckets))
+	}
+
+	// After a full refill period only the bucket still short of tokens is kept
+	clock.now = clock.now.Add(1500 * time.Millisecond)
+	limiter.Allow("busy")
+	clock.now = clock.now.Add(500 * time.Millisecond)
+	limiter.Allow("other")
+	if _, ok := limiter.buckets["busy"]; !ok || len(limiter.buckets) != 2 {
+		t.Errorf("Expected only the busy and other buckets to be kept, got %d buckets", len(limiter.buckets))
+	}
+
+	// Pruning must not reset a limited client
+	for limiter.Allow("busy") {
+	}
+	clock.now = clock.now.Add(2 * time.Second)
+	limiter.Allow("other")
+	allowed := 0
+	for limiter.Allow("busy") {
+		allowed++
+	}
+	if allowed != 4 {
+		t.Errorf("Expected the pruned bucket to start full with 4 tokens, got %d", allowed)
+	}
+}
+
+func TestAnalyticsRateLimitsPerClient(t *testing.T) {
+	log.Println("Starting TestAnalyticsRateLimitsPerClient")
+	server := NewServerWithOptions(10, ServerOptions{RateLimit: 0.001, RateBurst: 2})
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	noisy := AnalyticsData{ClientName: "noisy", OperationBody: `{ a }`}
+	for i := 0; i < 2; i++ {
+		if status := postEvent(t, ts.URL, noisy); status != http.StatusOK {
+			t.Fatalf("Event %d: expected status 200, got %d", i, status)
+		}
+	}
+	if status := postEvent(t, ts.URL, noisy); status != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 for the noisy client, got %d", status)
+	}
+	if status := postEvent(t, ts.URL, AnalyticsData{ClientName: "quiet", OperationBody: `{ a }`}); status != http.StatusOK {
+		t.Errorf("Expected status 200 for another client, got %d", status)
+	}
+
+	if got := getStats(t, ts.URL).EventsLimited; got != 1 {
+		t.Errorf("Expected 1 rate limited event, got %d", got)
+	}
+}
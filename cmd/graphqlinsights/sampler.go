@@ -0,0 +1,66 @@
+// Package main provides the entry point for the GraphQL Insights application
+package main
+
+import (
+	"math"
+	"sync"
+
+	"github.com/tom/graphqlinsights/pkg/parser"
+)
+
+// maxSampledFingerprints bounds the number of query shapes a Sampler remembers;
+// once reached, the history is cleared and every shape counts as new again
+const maxSampledFingerprints = 10000
+
+// Sampler decides which analytics events are processed when only a fraction of
+// traffic is needed. It is safe for concurrent use by multiple handlers.
+type Sampler struct {
+	mu           sync.Mutex
+	rate         float64
+	keepDistinct bool
+	random       func() float64 // Returns values in [0, 1)
+	seen         map[string]int // Events seen per query fingerprint in keepDistinct mode
+}
+
+// NewSampler creates a sampler that keeps roughly rate (between 0 and 1) of all
+// events, using random to pick them. If keepDistinct is set the decision is
+// deterministic per query fingerprint instead: the first event of every distinct
+// query is kept, followed by every 1/rate-th repeat of it. Events whose query
+// cannot be parsed fall back to random sampling.
+func NewSampler(rate float64, keepDistinct bool, random func() float64) *Sampler {
+	return &Sampler{
+		rate:         rate,
+		keepDistinct: keepDistinct,
+		random:       random,
+		seen:         make(map[string]int),
+	}
+}
+
+// Keep reports whether the event with the given query should be processed
+func (s *Sampler) Keep(query string) bool {
+	if !s.keepDistinct {
+		return s.sample()
+	}
+	document, err := parser.NewParser(query).ParseDocument()
+	if err != nil {
+		return s.sample()
+	}
+	fingerprint := document.Fingerprint()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.seen) >= maxSampledFingerprints {
+		clear(s.seen)
+	}
+	count := s.seen[fingerprint]
+	s.seen[fingerprint]++
+	every := max(int(math.Round(1/s.rate)), 1)
+	return count%every == 0
+}
+
+// sample keeps an event with probability rate
+func (s *Sampler) sample() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.random() < s.rate
+}
@@ -0,0 +1,82 @@
+// Package main provides the entry point for the GraphQL Insights application
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSamplerRandom(t *testing.T) {
+	log.Println("Starting TestSamplerRandom")
+	const events = 10000
+	for _, rate := range []float64{0.1, 0.5} {
+		t.Run(fmt.Sprint(rate), func(t *testing.T) {
+			sampler := NewSampler(rate, false, rand.New(rand.NewSource(1)).Float64)
+			kept := 0
+			for i := 0; i < events; i++ {
+				if sampler.Keep(`{ viewer { id } }`) {
+					kept++
+				}
+			}
+			if got := float64(kept) / events; got < rate*0.9 || got > rate*1.1 {
+				t.Errorf("Expected roughly %.0f%% of events kept, got %.1f%%", rate*100, got*100)
+			}
+		})
+	}
+}
+
+func TestSamplerKeepDistinctQueries(t *testing.T) {
+	log.Println("Starting TestSamplerKeepDistinctQueries")
+	// The random source must not be consulted for queries that parse
+	sampler := NewSampler(0.25, true, func() float64 {
+		t.Fatalf("Unexpected random sampling")
+		return 0
+	})
+
+	kept := make(map[string][]int)
+	for i := 0; i < 12; i++ {
+		for _, query := range []string{`{ viewer { id } }`, `{ user(id: 1) { name } }`} {
+			if sampler.Keep(query) {
+				kept[query] = append(kept[query], i)
+			}
+		}
+		// Same shape with a different literal shares the fingerprint of the second query
+		if i == 0 && sampler.Keep(`{ user(id: 2) { name } }`) {
+			t.Errorf("Expected the repeat of a query shape to be sampled out")
+		}
+	}
+
+	if got := kept[`{ viewer { id } }`]; fmt.Sprint(got) != "[0 4 8]" {
+		t.Errorf("Expected the viewer query kept on events [0 4 8], got %v", got)
+	}
+	// The extra user event shifted its repeats by one
+	if got := kept[`{ user(id: 1) { name } }`]; fmt.Sprint(got) != "[0 3 7 11]" {
+		t.Errorf("Expected the user query kept on events [0 3 7 11], got %v", got)
+	}
+}
+
+func TestServerSampling(t *testing.T) {
+	log.Println("Starting TestServerSampling")
+	const events = 1000
+	server := NewServerWithOptions(events, ServerOptions{SampleRate: 0.1})
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	for i := 0; i < events; i++ {
+		if status := postEvent(t, ts.URL, AnalyticsData{OperationBody: `{ viewer { id } }`}); status != http.StatusOK {
+			t.Fatalf("Expected unsampled events to be acknowledged, got status %d", status)
+		}
+	}
+
+	queued := len(server.queue)
+	if queued < 60 || queued > 140 {
+		t.Errorf("Expected roughly 100 of %d events queued, got %d", events, queued)
+	}
+	if got := server.Stats().EventsSampled; got != events-queued {
+		t.Errorf("Expected %d events sampled out, got %d", events-queued, got)
+	}
+}
@@ -0,0 +1,560 @@
+// Package main provides the entry point for the GraphQL Insights application
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/tom/graphqlinsights/pkg/analytics"
+	"github.com/tom/graphqlinsights/pkg/parser"
+)
+
+// topFieldsLimit is the number of fields reported by the stats endpoint
+const topFieldsLimit = 10
+
+// shutdownTimeout bounds how long in-flight HTTP requests may take to finish on shutdown
+const shutdownTimeout = 10 * time.Second
+
+// redactedArguments names the arguments whose values are hidden in logged queries
+var redactedArguments = []string{"email", "password", "token", "secret", "phone"}
+
+// maxBatchLineSize bounds the size of a single event in a batch request
+const maxBatchLineSize = 1 << 20
+
+// Size limits used when the corresponding ServerOptions are zero
+const (
+	DefaultMaxBodyBytes      = 1 << 20
+	DefaultMaxOperationBytes = 256 << 10
+)
+
+// Reasons an event is not queued
+var (
+	errRateLimited = errors.New("rate limit exceeded for client, try again later")
+	errQueueFull   = errors.New("event queue is full, try again later")
+	errTooLarge    = errors.New("operation body is too large")
+	errClosed      = errors.New("server is shutting down, try again later")
+)
+
+// parserPool reuses parsers across events to avoid reallocating them for every event
+var parserPool = sync.Pool{
+	New: func() any { return parser.NewParser("") },
+}
+
+// StatsResponse is the JSON body returned by the stats endpoint
+type StatsResponse struct {
+	EventsProcessed int                     `json:"events_processed"`
+	EventsDropped   int                     `json:"events_dropped"`
+	EventsDeduped   int                     `json:"events_deduplicated"`
+	EventsLimited   int                     `json:"events_rate_limited"`
+	EventsSampled   int                     `json:"events_sampled_out"`
+	BytesProcessed  int64                   `json:"bytes_processed"`
+	AvgQuerySize    float64                 `json:"average_query_size"`
+	Operations      map[string]int          `json:"operations"`
+	OperationTypes  map[string]int          `json:"operation_types"`
+	TopFields       []analytics.FieldCount  `json:"top_fields"`
+	Duplicates      []analytics.DedupRecord `json:"duplicates,omitempty"`
+}
+
+// BatchResponse is the JSON body returned by the batch endpoint
+type BatchResponse struct {
+	Accepted int      `json:"accepted"`
+	Rejected int      `json:"rejected"`
+	Errors   []string `json:"errors,omitempty"` // Why each rejected line was rejected
+}
+
+// ServerOptions configures optional server behaviour
+type ServerOptions struct {
+	// DedupWindow collapses identical operations from the same client seen within
+	// the window into a single processed event; zero disables deduplication
+	DedupWindow time.Duration
+
+	// RateLimit is the number of events per second each client may send, with
+	// bursts of up to RateBurst events; zero disables rate limiting
+	RateLimit float64
+	RateBurst int
+
+	// SampleRate is the fraction of events, between 0 and 1, that are processed;
+	// the rest are acknowledged but never queued. Zero or one disables sampling.
+	// With KeepDistinctQueries the first event of every distinct query is always
+	// processed and its repeats are sampled deterministically.
+	SampleRate          float64
+	KeepDistinctQueries bool
+
+	// MaxBodyBytes caps the size of a request to the analytics endpoint and
+	// MaxOperationBytes the length of an event's operation body, so concurrent
+	// oversized events cannot exhaust memory. Zero means DefaultMaxBodyBytes and
+	// DefaultMaxOperationBytes.
+	MaxBodyBytes      int64
+	MaxOperationBytes int
+
+	// NameAnonymousOperations reports each anonymous operation under a synthetic
+	// name such as anon_1a2b3c4d derived from its fingerprint, so distinct
+	// anonymous queries are told apart; otherwise they share one name
+	NameAnonymousOperations bool
+
+	// Notifier receives an alert for every processed operation whose depth or
+	// complexity exceeds Thresholds; nil disables alerts. Serve closes it once
+	// the workers have stopped.
+	Thresholds analytics.Thresholds
+	Notifier   *analytics.WebhookNotifier
+
+	// Rollup receives the fingerprint, client, and depth of every processed
+	// operation; nil disables rollups. Serve runs it until its context is done.
+	Rollup *analytics.HourlyRollup
+
+	// Logger receives one structured record per processed event; nil logs JSON
+	// lines to standard error
+	Logger *slog.Logger
+}
+
+// Server accepts analytics events over HTTP and processes them with a pool of workers
+type Server struct {
+	queue  chan AnalyticsData // Buffered channel for events
+	wg     sync.WaitGroup
+	fields *analytics.FieldStats      // Field usage across all processed events
+	dedup  *analytics.Deduplicator    // Nil when deduplication is disabled
+	limit  *RateLimiter               // Nil when rate limiting is disabled
+	sample *Sampler                   // Nil when sampling is disabled
+	notify *analytics.WebhookNotifier // Nil when alerts are disabled
+	rollup *analytics.HourlyRollup    // Nil when rollups are disabled
+
+	thresholds analytics.Thresholds
+
+	maxBodyBytes      int64
+	maxOperationBytes int
+	nameAnonymous     bool // Whether anonymous operations get synthetic names
+	logger            *slog.Logger
+
+	mu          sync.Mutex
+	ready       bool // Whether workers are running and the server is not shutting down
+	closed      bool // Whether the queue has been closed, after which nothing may be sent to it
+	received    int  // Events submitted to the analytics endpoints, whether queued or not
+	processed   int
+	parseErrors int // Processed events whose query could not be parsed
+	panics      int // Events whose processing panicked
+	dropped     int // Events rejected because the queue was full
+	deduped     int // Events collapsed into an earlier identical event
+	limited     int // Events rejected because their client exceeded its rate limit
+	sampledOut  int // Events acknowledged but not queued because sampling skipped them
+	operations  map[string]int
+	depths      *histogram // Depth of every successfully parsed query
+}
+
+// NewServer creates a server whose event queue buffers up to queueSize events
+func NewServer(queueSize int) *Server {
+	return NewServerWithOptions(queueSize, ServerOptions{})
+}
+
+// NewServerWithOptions creates a server with the given options
+func NewServerWithOptions(queueSize int, options ServerOptions) *Server {
+	s := &Server{
+		queue:      make(chan AnalyticsData, queueSize),
+		fields:     analytics.NewFieldStats(),
+		operations: make(map[string]int),
+		depths:     newHistogram(depthBuckets),
+		logger:     options.Logger,
+
+		nameAnonymous: options.NameAnonymousOperations,
+		notify:        options.Notifier,
+		thresholds:    options.Thresholds,
+		rollup:        options.Rollup,
+
+		maxBodyBytes:      cmp.Or(options.MaxBodyBytes, DefaultMaxBodyBytes),
+		maxOperationBytes: cmp.Or(options.MaxOperationBytes, DefaultMaxOperationBytes),
+	}
+	if s.logger == nil {
+		s.logger = slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	}
+	if options.DedupWindow > 0 {
+		s.dedup = analytics.NewDeduplicator(analytics.SystemClock{}, options.DedupWindow)
+	}
+	if options.SampleRate > 0 && options.SampleRate < 1 {
+		s.sample = NewSampler(options.SampleRate, options.KeepDistinctQueries, rand.Float64)
+	}
+	if options.RateLimit > 0 {
+		s.limit = NewRateLimiter(analytics.SystemClock{}, options.RateLimit, max(options.RateBurst, 1))
+	}
+	return s
+}
+
+// Start launches numWorkers workers that process queued events
+func (s *Server) Start(numWorkers int) {
+	s.wg.Add(numWorkers)
+	for i := 1; i <= numWorkers; i++ {
+		go s.worker(i)
+	}
+	s.setReady(numWorkers > 0)
+}
+
+// Handler returns the HTTP routes served by the server
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/analytics", s.handleAnalytics)
+	mux.HandleFunc("/analytics/batch", s.handleAnalyticsBatch)
+	mux.HandleFunc("/stats", s.handleStats)
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	mux.HandleFunc("/healthz", s.handleHealth)
+	mux.HandleFunc("/readyz", s.handleReady)
+	return mux
+}
+
+// Serve handles HTTP requests on listener until ctx is cancelled, then shuts down
+// gracefully: it stops accepting requests, waits for in-flight requests, closes the
+// event queue, and waits for the workers to process every queued event.
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	if s.rollup != nil {
+		go s.rollup.Run(ctx)
+	}
+	httpServer := &http.Server{Handler: s.Handler()}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.Serve(listener)
+	}()
+
+	var err error
+	select {
+	case err = <-serveErr:
+	case <-ctx.Done():
+		log.Println("Shutting down server")
+		s.setReady(false)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		err = httpServer.Shutdown(shutdownCtx)
+	}
+
+	// Drain the queue. Handlers still running after a shutdown timeout check the
+	// closed flag under the same lock before sending, so they cannot send to it.
+	s.mu.Lock()
+	s.closed = true
+	close(s.queue)
+	s.mu.Unlock()
+	s.wg.Wait()
+	if s.notify != nil {
+		s.notify.Close()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// worker processes events from the queue until it is closed
+func (s *Server) worker(id int) {
+	defer s.wg.Done()
+	for event := range s.queue {
+		s.processSafely(id, event)
+	}
+}
+
+// processSafely processes an event, recovering from any panic so that a single
+// bad event is logged and skipped instead of stopping the worker
+func (s *Server) processSafely(id int, event AnalyticsData) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.mu.Lock()
+			s.panics++
+			s.mu.Unlock()
+			s.logger.Error("recovered from panic while processing event",
+				slog.Int("worker", id),
+				slog.String("operation", event.OperationName),
+				slog.Any("panic", r),
+				slog.String("stack", string(debug.Stack())),
+			)
+		}
+	}()
+	s.process(id, event)
+}
+
+// process parses a single event and records its usage statistics
+func (s *Server) process(id int, event AnalyticsData) {
+	s.fields.RecordQuerySize(len(event.OperationBody))
+	p := parserPool.Get().(*parser.Parser)
+	p.Reset(event.OperationBody)
+	document, err := p.ParseDocument()
+	p.Reset("") // Do not keep the event's body alive while the parser sits in the pool
+	parserPool.Put(p)
+	var fingerprint string
+	if err == nil && (s.dedup != nil || s.rollup != nil) {
+		fingerprint = document.Fingerprint()
+	}
+	duplicate := err == nil && s.dedup != nil && !s.dedup.Observe(fingerprint, event.ClientName)
+	depth := 0
+	if err == nil {
+		depth = document.Depth()
+	}
+
+	s.mu.Lock()
+	s.processed++
+	if err != nil {
+		s.parseErrors++
+	} else {
+		s.depths.observe(float64(depth))
+	}
+	if duplicate {
+		s.deduped++
+	} else if err == nil {
+		for _, operation := range document.Operations() {
+			s.operations[s.statsName(operation)]++
+		}
+	}
+	s.mu.Unlock()
+	if s.rollup != nil && err == nil && !duplicate {
+		s.rollup.Record(fingerprint, event.ClientName, depth)
+	}
+
+	attrs := []any{
+		slog.Int("worker", id),
+		slog.Int64("timestamp", event.Timestamp),
+		slog.String("client", event.ClientName),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("operation", event.OperationName), slog.String("error", err.Error()))
+		if parseErr, ok := err.(*parser.ParseError); ok {
+			attrs = append(attrs, slog.String("snippet", parseErr.Snippet))
+		}
+		s.logger.Error("could not parse query", attrs...)
+		return
+	}
+
+	// Backfill the operation name when the client did not send one
+	if operations := document.Operations(); event.OperationName == "" && len(operations) > 0 {
+		event.OperationName = operations[0].Name
+		if event.OperationName == "" && s.nameAnonymous {
+			event.OperationName = anonymousName(operations[0])
+		}
+	}
+	attrs = append(attrs,
+		slog.String("operation", event.OperationName),
+		slog.Int("fields", fieldCount(document)),
+		slog.Int("depth", depth),
+		slog.String("query", parser.Redact(document, redactedArguments).String()),
+	)
+	if duplicate {
+		s.logger.Info("duplicate event", append(attrs, slog.Bool("duplicate", true))...)
+		return
+	}
+
+	if duplicates := parser.FindDuplicateFields(document); len(duplicates) > 0 {
+		s.logger.Warn("duplicate field selections", append(attrs, slog.Any("duplicate_fields", duplicates))...)
+	}
+	s.fields.Record(document)
+	s.checkThresholds(event, document, depth)
+	s.logger.Info("processed event", attrs...)
+}
+
+// checkThresholds sends an alert if the document exceeds the configured depth or complexity
+func (s *Server) checkThresholds(event AnalyticsData, document *parser.Node, depth int) {
+	if s.notify == nil {
+		return
+	}
+	complexity := document.Complexity()
+	reasons := s.thresholds.Check(depth, complexity)
+	if len(reasons) == 0 {
+		return
+	}
+	s.notify.Notify(analytics.Alert{
+		OperationName: event.OperationName,
+		ClientName:    event.ClientName,
+		Depth:         depth,
+		Complexity:    complexity,
+		Reasons:       reasons,
+	})
+}
+
+// fieldCount returns the number of fields selected anywhere in the document
+func fieldCount(document *parser.Node) int {
+	count := 0
+	parser.Walk(document, func(n *parser.Node) bool {
+		if n.Type == parser.NodeField {
+			count++
+		}
+		return true
+	})
+	return count
+}
+
+// operationName returns the name an operation is reported under
+func operationName(operation *parser.Node) string {
+	if operation.Name == "" {
+		return "(anonymous)"
+	}
+	return operation.Name
+}
+
+// statsName returns the name an operation is counted under in stats, which is a
+// synthetic name for anonymous operations when NameAnonymousOperations is set
+func (s *Server) statsName(operation *parser.Node) string {
+	if operation.Name == "" && s.nameAnonymous {
+		return anonymousName(operation)
+	}
+	return operationName(operation)
+}
+
+// anonymousName returns the synthetic name of an anonymous operation, derived
+// from its fingerprint so that operations of the same shape share a name
+func anonymousName(operation *parser.Node) string {
+	return "anon_" + operation.Fingerprint()[:8]
+}
+
+// handleAnalytics accepts an analytics event and queues it for processing
+func (s *Server) handleAnalytics(w http.ResponseWriter, r *http.Request) {
+	var data AnalyticsData
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.maxBodyBytes)).Decode(&data); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("request body is larger than %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	switch err := s.enqueue(data); err {
+	case nil:
+		fmt.Fprintf(w, "Data received")
+	case errTooLarge:
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errRateLimited:
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	default:
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	}
+}
+
+// handleAnalyticsBatch accepts newline-delimited JSON events, decoding and queueing
+// them one line at a time so the body is never buffered whole. Blank lines are
+// skipped. It reports how many events were accepted and why the others were rejected.
+func (s *Server) handleAnalyticsBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var response BatchResponse
+	reject := func(line int, err error) {
+		response.Rejected++
+		response.Errors = append(response.Errors, fmt.Sprintf("line %d: %s", line, err))
+	}
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(nil, maxBatchLineSize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var data AnalyticsData
+		if err := json.Unmarshal(scanner.Bytes(), &data); err != nil {
+			reject(line, err)
+			continue
+		}
+		if err := s.enqueue(data); err != nil {
+			reject(line, err)
+			continue
+		}
+		response.Accepted++
+	}
+
+	status := http.StatusOK
+	if err := scanner.Err(); err != nil {
+		// The rest of the body cannot be read, so its events are neither accepted nor rejected
+		reject(line+1, err)
+		status = http.StatusBadRequest
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Could not write batch response: %s", err)
+	}
+}
+
+// enqueue queues an event for the workers without blocking, returning
+// errTooLarge, errRateLimited, errQueueFull, or errClosed if it cannot be queued.
+// Events skipped by sampling are acknowledged without being queued.
+func (s *Server) enqueue(data AnalyticsData) error {
+	s.mu.Lock()
+	s.received++
+	s.mu.Unlock()
+	if len(data.OperationBody) > s.maxOperationBytes {
+		return errTooLarge
+	}
+	if s.limit != nil && !s.limit.Allow(data.ClientName) {
+		s.mu.Lock()
+		s.limited++
+		s.mu.Unlock()
+		return errRateLimited
+	}
+	if s.sample != nil && !s.sample.Keep(data.OperationBody) {
+		s.mu.Lock()
+		s.sampledOut++
+		s.mu.Unlock()
+		return nil
+	}
+
+	// Send under the lock so that Serve cannot close the queue in between
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errClosed
+	}
+	select {
+	case s.queue <- data:
+		return nil
+	default:
+		s.dropped++
+		return errQueueFull
+	}
+}
+
+// handleStats reports the statistics aggregated so far as JSON
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.Stats()); err != nil {
+		log.Printf("Could not write stats response: %s", err)
+	}
+}
+
+// Stats returns a snapshot of the statistics aggregated so far
+func (s *Server) Stats() StatsResponse {
+	s.mu.Lock()
+	stats := StatsResponse{
+		EventsProcessed: s.processed,
+		EventsDropped:   s.dropped,
+		EventsDeduped:   s.deduped,
+		EventsLimited:   s.limited,
+		EventsSampled:   s.sampledOut,
+		Operations:      make(map[string]int, len(s.operations)),
+	}
+	for name, count := range s.operations {
+		stats.Operations[name] = count
+	}
+	s.mu.Unlock()
+	stats.BytesProcessed = s.fields.TotalQueryBytes()
+	stats.AvgQuerySize = s.fields.AverageQuerySize()
+	stats.OperationTypes = s.fields.OperationTypeCounts()
+	stats.TopFields = s.fields.TopN(topFieldsLimit)
+	if s.dedup != nil {
+		stats.Duplicates = s.dedup.Records()
+	}
+	return stats
+}
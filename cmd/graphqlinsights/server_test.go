@@ -0,0 +1,798 @@
+// Package main provides the entry point for the GraphQL Insights application
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tom/graphqlinsights/pkg/analytics"
+	"github.com/tom/graphqlinsights/pkg/parser"
+)
+
+// postEvent sends an analytics event to the server and returns the response status
+func postEvent(t *testing.T, url string, event AnalyticsData) int {
+	t.Helper()
+	return postEventWith(t, http.DefaultClient, url, event)
+}
+
+// postEventWith is like postEvent, but sends the event with the given client
+func postEventWith(t *testing.T, client *http.Client, url string, event AnalyticsData) int {
+	t.Helper()
+	body, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("Could not encode event: %s", err)
+	}
+	resp, err := client.Post(url+"/analytics", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Could not post event: %s", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+// serve runs server.Serve on a local listener until the returned stop function
+// is called. It returns the server's URL and a client of its own, whose idle
+// connections stop closes first so that Shutdown never waits on them.
+func serve(t *testing.T, server *Server) (url string, client *http.Client, stop func()) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Could not listen: %s", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Serve(ctx, listener)
+	}()
+
+	client = &http.Client{Transport: &http.Transport{}}
+	stop = func() {
+		t.Helper()
+		client.CloseIdleConnections()
+		cancel()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("Unexpected serve error: %s", err)
+			}
+		case <-time.After(shutdownTimeout + 5*time.Second):
+			t.Fatalf("Server did not shut down")
+		}
+	}
+	return "http://" + listener.Addr().String(), client, stop
+}
+
+// getStats fetches and decodes the stats endpoint
+func getStats(t *testing.T, url string) StatsResponse {
+	t.Helper()
+	resp, err := http.Get(url + "/stats")
+	if err != nil {
+		t.Fatalf("Could not get stats: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	var stats StatsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		t.Fatalf("Could not decode stats: %s", err)
+	}
+	return stats
+}
+
+func TestStatsEndpoint(t *testing.T) {
+	log.Println("Starting TestStatsEndpoint")
+	server := NewServer(10)
+	server.Start(2)
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	events := []string{
+		`query GetUser { user(id: "1") { name email } }`,
+		`query GetUser { user(id: "2") { name } }`,
+		`{ viewer { name } }`,
+		`mutation Like { like(id: "1") { ok } }`,
+		`query {`,
+	}
+	for _, body := range events {
+		if status := postEvent(t, ts.URL, AnalyticsData{OperationBody: body}); status != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", status)
+		}
+	}
+
+	var stats StatsResponse
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		stats = getStats(t, ts.URL)
+		if stats.EventsProcessed == len(events) || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if stats.EventsProcessed != len(events) {
+		t.Fatalf("Expected %d events processed, got %d", len(events), stats.EventsProcessed)
+	}
+	var wantBytes int
+	for _, body := range events {
+		wantBytes += len(body)
+	}
+	if stats.BytesProcessed != int64(wantBytes) {
+		t.Errorf("Expected %d bytes processed, got %d", wantBytes, stats.BytesProcessed)
+	}
+	if want := float64(wantBytes) / float64(len(events)); stats.AvgQuerySize != want {
+		t.Errorf("Expected average query size %v, got %v", want, stats.AvgQuerySize)
+	}
+	wantOperations := map[string]int{"GetUser": 2, "(anonymous)": 1, "Like": 1}
+	if !reflect.DeepEqual(stats.Operations, wantOperations) {
+		t.Errorf("Expected operations %v, got %v", wantOperations, stats.Operations)
+	}
+	wantOperationTypes := map[string]int{"query": 3, "mutation": 1}
+	if !reflect.DeepEqual(stats.OperationTypes, wantOperationTypes) {
+		t.Errorf("Expected operation types %v, got %v", wantOperationTypes, stats.OperationTypes)
+	}
+	wantFields := []analytics.FieldCount{
+		{Name: "name", Count: 3},
+		{Name: "user", Count: 2},
+		{Name: "email", Count: 1},
+		{Name: "like", Count: 1},
+		{Name: "ok", Count: 1},
+		{Name: "viewer", Count: 1},
+	}
+	if !reflect.DeepEqual(stats.TopFields, wantFields) {
+		t.Errorf("Expected top fields %v, got %v", wantFields, stats.TopFields)
+	}
+}
+
+func TestStatsEndpointRejectsPost(t *testing.T) {
+	log.Println("Starting TestStatsEndpointRejectsPost")
+	ts := httptest.NewServer(NewServer(1).Handler())
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/stats", "application/json", nil)
+	if err != nil {
+		t.Fatalf("Could not post to stats: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", resp.StatusCode)
+	}
+}
+
+func TestServeDrainsQueueOnShutdown(t *testing.T) {
+	log.Println("Starting TestServeDrainsQueueOnShutdown")
+	const numEvents = 50
+	server := NewServer(numEvents)
+	server.Start(1)
+
+	url, client, stop := serve(t, server)
+	for i := 0; i < numEvents; i++ {
+		event := AnalyticsData{OperationBody: `query GetUser { user(id: "1") { name } }`}
+		if status := postEventWith(t, client, url, event); status != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", status)
+		}
+	}
+
+	stop()
+
+	if got := server.Stats().EventsProcessed; got != numEvents {
+		t.Errorf("Expected %d events processed after shutdown, got %d", numEvents, got)
+	}
+	if _, err := client.Get(url + "/stats"); err == nil {
+		t.Errorf("Expected the server to stop accepting requests")
+	}
+
+	// A handler still running after the shutdown timeout must not send to the closed queue
+	late := httptest.NewServer(server.Handler())
+	defer late.Close()
+	if status := postEvent(t, late.URL, AnalyticsData{OperationBody: `{ a }`}); status != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 after shutdown, got %d", status)
+	}
+}
+
+func TestAnalyticsRejectsWhenQueueFull(t *testing.T) {
+	log.Println("Starting TestAnalyticsRejectsWhenQueueFull")
+	// No workers are started, so the queue only ever fills up
+	server := NewServer(2)
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	event := AnalyticsData{OperationBody: `{ viewer { id } }`}
+	for i := 0; i < 2; i++ {
+		if status := postEvent(t, ts.URL, event); status != http.StatusOK {
+			t.Fatalf("Event %d: expected status 200, got %d", i, status)
+		}
+	}
+
+	body, _ := json.Marshal(event)
+	resp, err := http.Post(ts.URL+"/analytics", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("Could not post event: %s", err)
+	}
+	message, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status 503, got %d", resp.StatusCode)
+	}
+	if !strings.Contains(string(message), "queue is full") {
+		t.Errorf("Expected a queue full message, got %q", message)
+	}
+
+	if got := getStats(t, ts.URL).EventsDropped; got != 1 {
+		t.Errorf("Expected 1 dropped event, got %d", got)
+	}
+}
+
+func TestDeduplicatesIdenticalEvents(t *testing.T) {
+	log.Println("Starting TestDeduplicatesIdenticalEvents")
+	server := NewServerWithOptions(10, ServerOptions{DedupWindow: time.Minute})
+	event := AnalyticsData{ClientName: "web", OperationBody: `query GetUser { user(id: "1") { name } }`}
+	for i := 0; i < 5; i++ {
+		server.process(1, event)
+	}
+	// A different literal shares the fingerprint, but another client is counted separately
+	server.process(1, AnalyticsData{ClientName: "ios", OperationBody: `query GetUser { user(id: "2") { name } }`})
+
+	stats := server.Stats()
+	if stats.EventsProcessed != 6 || stats.EventsDeduped != 4 {
+		t.Errorf("Expected 6 processed and 4 deduplicated, got %d and %d", stats.EventsProcessed, stats.EventsDeduped)
+	}
+	if got := stats.Operations["GetUser"]; got != 2 {
+		t.Errorf("Expected GetUser to be recorded twice, got %d", got)
+	}
+	if len(stats.Duplicates) != 2 {
+		t.Fatalf("Expected 2 dedup records, got %+v", stats.Duplicates)
+	}
+	counts := map[string]int{}
+	for _, record := range stats.Duplicates {
+		counts[record.Client] = record.Count
+	}
+	if counts["web"] != 5 || counts["ios"] != 1 {
+		t.Errorf("Expected counts web=5 ios=1, got %v", counts)
+	}
+}
+
+func TestWorkerLogsStructuredJSON(t *testing.T) {
+	log.Println("Starting TestWorkerLogsStructuredJSON")
+	var buf bytes.Buffer
+	server := NewServerWithOptions(10, ServerOptions{Logger: slog.New(slog.NewJSONHandler(&buf, nil))})
+
+	server.process(3, AnalyticsData{Timestamp: 1700000000, ClientName: "web", OperationBody: `query GetUser { user(id: "1") { name friends { name } } }`})
+	server.process(4, AnalyticsData{Timestamp: 1700000001, ClientName: "ios", OperationName: "Broken", OperationBody: `query {`})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 log lines, got %d: %s", len(lines), buf.String())
+	}
+	var processed, failed map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &processed); err != nil {
+		t.Fatalf("Expected valid JSON, got %s: %s", lines[0], err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &failed); err != nil {
+		t.Fatalf("Expected valid JSON, got %s: %s", lines[1], err)
+	}
+
+	wantProcessed := map[string]any{
+		"level":     "INFO",
+		"worker":    float64(3),
+		"timestamp": float64(1700000000),
+		"client":    "web",
+		"operation": "GetUser",
+		"fields":    float64(4),
+		"depth":     float64(3),
+	}
+	for key, want := range wantProcessed {
+		if processed[key] != want {
+			t.Errorf("Processed record %s: expected %v, got %v", key, want, processed[key])
+		}
+	}
+	for _, key := range []string{"time", "msg"} {
+		if _, ok := processed[key]; !ok {
+			t.Errorf("Processed record is missing %s", key)
+		}
+	}
+
+	if failed["level"] != "ERROR" || failed["worker"] != float64(4) || failed["operation"] != "Broken" {
+		t.Errorf("Unexpected parse error record %v", failed)
+	}
+	if message, _ := failed["error"].(string); !strings.Contains(message, "Unexpected token") {
+		t.Errorf("Expected the parse error to be logged, got %v", failed["error"])
+	}
+	if failed["snippet"] != "query {" {
+		t.Errorf("Expected the input around the error to be logged, got %v", failed["snippet"])
+	}
+}
+
+func TestWorkerWarnsOnDuplicateFields(t *testing.T) {
+	log.Println("Starting TestWorkerWarnsOnDuplicateFields")
+	var buf bytes.Buffer
+	server := NewServerWithOptions(10, ServerOptions{Logger: slog.New(slog.NewJSONHandler(&buf, nil))})
+
+	server.process(1, AnalyticsData{ClientName: "web", OperationBody: `{ user { name name } }`})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected a warning and a processed record, got %s", buf.String())
+	}
+	var warning map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &warning); err != nil {
+		t.Fatalf("Expected valid JSON, got %s: %s", lines[0], err)
+	}
+	if warning["level"] != "WARN" || warning["client"] != "web" {
+		t.Errorf("Unexpected warning record %v", warning)
+	}
+	if fields, _ := warning["duplicate_fields"].([]any); len(fields) != 1 || fields[0] != "user.name" {
+		t.Errorf("Expected duplicate_fields [user.name], got %v", warning["duplicate_fields"])
+	}
+}
+
+func TestWorkerRedactsLoggedQuery(t *testing.T) {
+	log.Println("Starting TestWorkerRedactsLoggedQuery")
+	var buf bytes.Buffer
+	server := NewServerWithOptions(10, ServerOptions{Logger: slog.New(slog.NewJSONHandler(&buf, nil))})
+
+	server.process(1, AnalyticsData{ClientName: "web", OperationBody: `{ account { login(email: "ada@example.com", remember: true) { id } } }`})
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected valid JSON, got %s: %s", buf.String(), err)
+	}
+	want := `{ account { login(email: ***, remember: true) { id } } }`
+	if record["query"] != want {
+		t.Errorf("Expected query %s, got %v", want, record["query"])
+	}
+}
+
+func TestWorkerNamesAnonymousOperations(t *testing.T) {
+	log.Println("Starting TestWorkerNamesAnonymousOperations")
+	var buf bytes.Buffer
+	server := NewServerWithOptions(10, ServerOptions{
+		NameAnonymousOperations: true,
+		Logger:                  slog.New(slog.NewJSONHandler(&buf, nil)),
+	})
+
+	events := []AnalyticsData{
+		{OperationBody: `{ user(id: 1) { name } }`},
+		{OperationBody: `{ user(id: 2) { name } }`},
+		{OperationBody: `{ user(id: 1) { email } }`},
+		{OperationBody: `mutation { like(id: 1) { ok } }`},
+		{OperationBody: `query Named { viewer { id } }`},
+		{OperationName: "FromClient", OperationBody: `{ viewer { id } }`},
+	}
+	for i, event := range events {
+		server.process(i+1, event)
+	}
+
+	// Queries differing only in literal values share a name
+	userName := anonymousName(mustParseOperation(t, events[0].OperationBody))
+	emailName := anonymousName(mustParseOperation(t, events[2].OperationBody))
+	likeName := anonymousName(mustParseOperation(t, events[3].OperationBody))
+	viewerName := anonymousName(mustParseOperation(t, events[5].OperationBody))
+	for _, name := range []string{userName, emailName, likeName, viewerName} {
+		if len(name) != len("anon_")+8 || !strings.HasPrefix(name, "anon_") {
+			t.Errorf("Expected a synthetic name like anon_1a2b3c4d, got %s", name)
+		}
+	}
+	wantOperations := map[string]int{userName: 2, emailName: 1, likeName: 1, viewerName: 1, "Named": 1}
+	if len(wantOperations) != 5 {
+		t.Fatalf("Expected distinct anonymous queries to get distinct names, got %v", wantOperations)
+	}
+	if got := server.Stats().Operations; !reflect.DeepEqual(got, wantOperations) {
+		t.Errorf("Expected operations %v, got %v", wantOperations, got)
+	}
+
+	// The logged name is only synthesized when the client sent none
+	var logged []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Expected valid JSON, got %s: %s", line, err)
+		}
+		logged = append(logged, record["operation"].(string))
+	}
+	wantLogged := []string{userName, userName, emailName, likeName, "Named", "FromClient"}
+	if !reflect.DeepEqual(logged, wantLogged) {
+		t.Errorf("Expected logged operations %v, got %v", wantLogged, logged)
+	}
+
+	unnamed := NewServerWithOptions(10, ServerOptions{Logger: slog.New(slog.NewJSONHandler(io.Discard, nil))})
+	unnamed.process(1, events[0])
+	unnamed.process(2, events[2])
+	if want := map[string]int{"(anonymous)": 2}; !reflect.DeepEqual(unnamed.Stats().Operations, want) {
+		t.Errorf("Expected %v without synthetic names, got %v", want, unnamed.Stats().Operations)
+	}
+}
+
+func TestWorkerProcessesRepeatedFragmentSpreadsQuickly(t *testing.T) {
+	log.Println("Starting TestWorkerProcessesRepeatedFragmentSpreadsQuickly")
+	// Each fragment spreads the next one twice, so following every spread would
+	// visit 2^40 fragments and hold the worker indefinitely
+	const fragments = 40
+	var body strings.Builder
+	body.WriteString("query Bomb { root { ...F0 } }")
+	for i := 0; i < fragments; i++ {
+		fmt.Fprintf(&body, " fragment F%d on T { a { ...F%d } b { ...F%d } }", i, i+1, i+1)
+	}
+	fmt.Fprintf(&body, " fragment F%d on T { leaf }", fragments)
+
+	alerts := make(chan analytics.Alert, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert analytics.Alert
+		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+			t.Errorf("Could not decode alert: %s", err)
+		}
+		alerts <- alert
+	}))
+	defer webhook.Close()
+	notifier := analytics.NewWebhookNotifier(analytics.WebhookConfig{URL: webhook.URL, Client: webhook.Client()})
+	var buf bytes.Buffer
+	server := NewServerWithOptions(10, ServerOptions{
+		Thresholds: analytics.Thresholds{MaxDepth: 10},
+		Notifier:   notifier,
+		Logger:     slog.New(slog.NewJSONHandler(&buf, nil)),
+	})
+
+	server.process(1, AnalyticsData{ClientName: "web", OperationBody: body.String()})
+	notifier.Close()
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected a single JSON record, got %s: %s", buf.String(), err)
+	}
+	if record["depth"] != float64(fragments+2) {
+		t.Errorf("Expected depth %d, got %v", fragments+2, record["depth"])
+	}
+	select {
+	case alert := <-alerts:
+		if alert.Depth != fragments+2 || alert.Complexity != math.MaxInt32 {
+			t.Errorf("Expected depth %d and complexity %d, got %+v", fragments+2, math.MaxInt32, alert)
+		}
+	default:
+		t.Errorf("Expected an alert for the deep operation")
+	}
+}
+
+// mustParseOperation parses a document and returns its first operation
+func mustParseOperation(t *testing.T, input string) *parser.Node {
+	t.Helper()
+	document, err := parser.NewParser(input).ParseDocument()
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+	return document.Operations()[0]
+}
+
+// panicHandler is a slog handler that panics on records whose query selects the
+// given field, standing in for a bug triggered by a single event
+type panicHandler struct {
+	slog.Handler
+	field string
+}
+
+func (h panicHandler) Handle(ctx context.Context, r slog.Record) error {
+	r.Attrs(func(attr slog.Attr) bool {
+		if attr.Key == "query" && strings.Contains(attr.Value.String(), h.field) {
+			panic("cannot handle " + h.field)
+		}
+		return true
+	})
+	return h.Handler.Handle(ctx, r)
+}
+
+func TestWorkerRecoversFromPanic(t *testing.T) {
+	log.Println("Starting TestWorkerRecoversFromPanic")
+	var buf bytes.Buffer
+	handler := panicHandler{Handler: slog.NewJSONHandler(&buf, nil), field: "explode"}
+	server := NewServerWithOptions(10, ServerOptions{Logger: slog.New(handler)})
+	server.Start(1)
+
+	for _, body := range []string{`{ explode }`, `{ first }`, `{ explode }`, `{ second }`} {
+		server.queue <- AnalyticsData{OperationName: "Op", OperationBody: body}
+	}
+	close(server.queue)
+	server.wg.Wait()
+
+	// The single worker must have survived both panics to process every event
+	stats := server.Stats()
+	if stats.EventsProcessed != 4 {
+		t.Errorf("Expected 4 events processed, got %d", stats.EventsProcessed)
+	}
+	fields := make(map[string]int)
+	for _, field := range stats.TopFields {
+		fields[field.Name] = field.Count
+	}
+	if fields["first"] != 1 || fields["second"] != 1 {
+		t.Errorf("Expected the events after the panics to be recorded, got %v", stats.TopFields)
+	}
+	if server.panics != 2 {
+		t.Errorf("Expected 2 recovered panics, got %d", server.panics)
+	}
+
+	var recovered []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Expected valid JSON, got %s: %s", line, err)
+		}
+		if record["msg"] == "recovered from panic while processing event" {
+			recovered = append(recovered, record)
+		}
+	}
+	if len(recovered) != 2 {
+		t.Fatalf("Expected 2 panic records, got %d: %s", len(recovered), buf.String())
+	}
+	record := recovered[0]
+	if record["level"] != "ERROR" || record["worker"] != float64(1) || record["operation"] != "Op" || record["panic"] != "cannot handle explode" {
+		t.Errorf("Unexpected panic record %v", record)
+	}
+	if stack, _ := record["stack"].(string); !strings.Contains(stack, "processSafely") {
+		t.Errorf("Expected the stack trace to be logged, got %v", record["stack"])
+	}
+}
+
+func TestAnalyticsBatch(t *testing.T) {
+	log.Println("Starting TestAnalyticsBatch")
+	body := strings.Join([]string{
+		`{"client_name": "web", "operation_body": "{ viewer { id } }"}`,
+		`{"client_name": "web", "operation_body": "query Feed { posts { title } }"}`,
+		``,
+		`{"client_name": "web", "operation_body": `,
+		`{"client_name": "ios", "operation_body": "{ viewer { name } }"}`,
+		`{"client_name": "ios", "operation_body": "{ viewer { email } }"}`,
+	}, "\n")
+	tests := []struct {
+		name      string
+		queueSize int
+		want      BatchResponse
+	}{
+		{
+			name:      "Malformed line",
+			queueSize: 10,
+			want: BatchResponse{Accepted: 4, Rejected: 1, Errors: []string{
+				"line 4: unexpected end of JSON input",
+			}},
+		},
+		{
+			name:      "Queue fills up",
+			queueSize: 3,
+			want: BatchResponse{Accepted: 3, Rejected: 2, Errors: []string{
+				"line 4: unexpected end of JSON input",
+				"line 6: event queue is full, try again later",
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// No workers are started, so accepted events stay queued
+			server := NewServer(tt.queueSize)
+			ts := httptest.NewServer(server.Handler())
+			defer ts.Close()
+
+			resp, err := http.Post(ts.URL+"/analytics/batch", "application/x-ndjson", strings.NewReader(body))
+			if err != nil {
+				t.Fatalf("Could not post batch: %s", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+			}
+			var got BatchResponse
+			if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+				t.Fatalf("Could not decode batch response: %s", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+			if queued := len(server.queue); queued != tt.want.Accepted {
+				t.Errorf("Expected %d queued events, got %d", tt.want.Accepted, queued)
+			}
+		})
+	}
+}
+
+func TestAnalyticsBatchErrors(t *testing.T) {
+	log.Println("Starting TestAnalyticsBatchErrors")
+	server := NewServer(10)
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/analytics/batch")
+	if err != nil {
+		t.Fatalf("Could not get batch endpoint: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", resp.StatusCode)
+	}
+
+	body := `{"operation_body": "{ a }"}` + "\n" + strings.Repeat("x", maxBatchLineSize+1)
+	resp, err = http.Post(ts.URL+"/analytics/batch", "application/x-ndjson", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Could not post batch: %s", err)
+	}
+	defer resp.Body.Close()
+	var got BatchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("Could not decode batch response: %s", err)
+	}
+	if resp.StatusCode != http.StatusBadRequest || got.Accepted != 1 || got.Rejected != 1 {
+		t.Errorf("Expected status 400 with 1 accepted and 1 rejected, got %d %+v", resp.StatusCode, got)
+	}
+}
+
+func TestAnalyticsSizeLimits(t *testing.T) {
+	log.Println("Starting TestAnalyticsSizeLimits")
+	server := NewServerWithOptions(10, ServerOptions{MaxBodyBytes: 200, MaxOperationBytes: 50})
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name:       "Within limits",
+			body:       `{"operation_body": "{ viewer { id } }"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Oversized body",
+			body:       `{"client_name": "` + strings.Repeat("x", 200) + `", "operation_body": "{ a }"}`,
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantError:  "request body is larger than 200 bytes",
+		},
+		{
+			name:       "Oversized operation",
+			body:       `{"operation_body": "{ ` + strings.Repeat("a ", 30) + `}"}`,
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantError:  "operation body is too large",
+		},
+		{
+			name:       "Malformed body",
+			body:       `{"operation_body": `,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(ts.URL+"/analytics", "application/json", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("Could not post event: %s", err)
+			}
+			message, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantStatus, resp.StatusCode, message)
+			}
+			if !strings.Contains(string(message), tt.wantError) {
+				t.Errorf("Expected message %q, got %q", tt.wantError, message)
+			}
+		})
+	}
+
+	if queued := len(server.queue); queued != 1 {
+		t.Errorf("Expected only the event within limits to be queued, got %d", queued)
+	}
+	if server.maxBodyBytes != 200 || NewServer(1).maxBodyBytes != DefaultMaxBodyBytes {
+		t.Errorf("Expected configured and default body limits to apply")
+	}
+}
+
+func TestServeSendsThresholdAlerts(t *testing.T) {
+	log.Println("Starting TestServeSendsThresholdAlerts")
+	alerts := make(chan analytics.Alert, 10)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert analytics.Alert
+		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+			t.Errorf("Could not decode alert: %s", err)
+		}
+		alerts <- alert
+	}))
+	defer webhook.Close()
+
+	server := NewServerWithOptions(10, ServerOptions{
+		Thresholds: analytics.Thresholds{MaxDepth: 2, MaxComplexity: 10},
+		Notifier:   analytics.NewWebhookNotifier(analytics.WebhookConfig{URL: webhook.URL, Client: webhook.Client()}),
+		Logger:     slog.New(slog.NewJSONHandler(io.Discard, nil)),
+	})
+	server.Start(1)
+
+	url, client, stop := serve(t, server)
+	events := []AnalyticsData{
+		{OperationName: "Shallow", ClientName: "web", OperationBody: `query Shallow { viewer { id } }`},
+		{OperationName: "Deep", ClientName: "ios", OperationBody: `query Deep { viewer { friends(first: 5) { name } } }`},
+	}
+	for _, event := range events {
+		if status := postEventWith(t, client, url, event); status != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", status)
+		}
+	}
+
+	// Serve closes the notifier, which delivers every queued alert before returning
+	stop()
+
+	want := analytics.Alert{
+		OperationName: "Deep",
+		ClientName:    "ios",
+		Depth:         3,
+		Complexity:    7,
+		Reasons:       []string{"depth 3 exceeds limit of 2"},
+	}
+	select {
+	case got := <-alerts:
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected alert %+v, got %+v", want, got)
+		}
+	default:
+		t.Fatalf("Expected an alert for the deep operation")
+	}
+	select {
+	case got := <-alerts:
+		t.Errorf("Expected a single alert, got another %+v", got)
+	default:
+	}
+}
+
+func TestServeWritesRollups(t *testing.T) {
+	log.Println("Starting TestServeWritesRollups")
+	store := analytics.NewMemoryStore()
+	server := NewServerWithOptions(10, ServerOptions{
+		Rollup: analytics.NewHourlyRollup(store, analytics.SystemClock{}, 20*time.Millisecond),
+		Logger: slog.New(slog.NewJSONHandler(io.Discard, nil)),
+	})
+	server.Start(1)
+
+	url, client, stop := serve(t, server)
+	events := []AnalyticsData{
+		{ClientName: "web", OperationBody: `{ user(id: 1) { name } }`},
+		{ClientName: "ios", OperationBody: `{ user(id: 2) { name } }`},
+		{ClientName: "web", OperationBody: `{ viewer { id } }`},
+		{ClientName: "web", OperationBody: `{ broken`},
+	}
+	for _, event := range events {
+		if status := postEventWith(t, client, url, event); status != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", status)
+		}
+	}
+
+	// Events may fall into either side of a window boundary, so sum the records
+	// per fingerprint once Run has flushed every parsed event
+	userFingerprint := mustParseOperation(t, events[0].OperationBody).Fingerprint()
+	viewerFingerprint := mustParseOperation(t, events[2].OperationBody).Fingerprint()
+	depths := map[string]float64{userFingerprint: 2, viewerFingerprint: 2}
+	counts := make(map[string]int)
+	deadline := time.Now().Add(5 * time.Second)
+	for counts[userFingerprint]+counts[viewerFingerprint] < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		clear(counts)
+		for _, record := range store.Rollups() {
+			counts[record.Fingerprint] += record.Count
+			if record.AverageDepth != depths[record.Fingerprint] {
+				t.Errorf("Expected average depth %v, got %v", depths[record.Fingerprint], record.AverageDepth)
+			}
+		}
+	}
+	stop()
+
+	want := map[string]int{userFingerprint: 2, viewerFingerprint: 1}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("Expected rollup counts %v, got %v", want, counts)
+	}
+}
@@ -0,0 +1,90 @@
+// Package analytics aggregates usage statistics for parsed GraphQL operations
+package analytics
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// DedupRecord counts the occurrences of one fingerprint from one client within a window
+type DedupRecord struct {
+	Fingerprint string    `json:"fingerprint"`
+	Client      string    `json:"client"`
+	Count       int       `json:"count"`
+	FirstSeen   time.Time `json:"first_seen"`
+}
+
+// dedupKey identifies the events that are collapsed together
+type dedupKey struct {
+	fingerprint string
+	client      string
+}
+
+// Deduplicator collapses identical events from the same client seen within a time
+// window, counting them instead of letting each one be processed again
+type Deduplicator struct {
+	mu        sync.Mutex
+	clock     Clock
+	window    time.Duration
+	records   map[dedupKey]*DedupRecord
+	lastPrune time.Time
+}
+
+// NewDeduplicator creates a deduplicator that collapses events within window
+func NewDeduplicator(clock Clock, window time.Duration) *Deduplicator {
+	return &Deduplicator{
+		clock:     clock,
+		window:    window,
+		records:   make(map[dedupKey]*DedupRecord),
+		lastPrune: clock.Now(),
+	}
+}
+
+// Observe records one occurrence of fingerprint from client and reports whether it
+// is the first within the current window and should therefore be processed
+func (d *Deduplicator) Observe(fingerprint, client string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := d.clock.Now()
+	if now.Sub(d.lastPrune) >= d.window {
+		d.prune(now)
+	}
+
+	key := dedupKey{fingerprint: fingerprint, client: client}
+	record, ok := d.records[key]
+	if ok && now.Sub(record.FirstSeen) < d.window {
+		record.Count++
+		return false
+	}
+	d.records[key] = &DedupRecord{Fingerprint: fingerprint, Client: client, Count: 1, FirstSeen: now}
+	return true
+}
+
+// prune drops records whose window has ended
+func (d *Deduplicator) prune(now time.Time) {
+	for key, record := range d.records {
+		if now.Sub(record.FirstSeen) >= d.window {
+			delete(d.records, key)
+		}
+	}
+	d.lastPrune = now
+}
+
+// Records returns the records of the current windows ordered by fingerprint and client
+func (d *Deduplicator) Records() []DedupRecord {
+	d.mu.Lock()
+	records := make([]DedupRecord, 0, len(d.records))
+	for _, record := range d.records {
+		records = append(records, *record)
+	}
+	d.mu.Unlock()
+
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Fingerprint != records[j].Fingerprint {
+			return records[i].Fingerprint < records[j].Fingerprint
+		}
+		return records[i].Client < records[j].Client
+	})
+	return records
+}
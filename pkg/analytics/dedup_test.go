@@ -0,0 +1,46 @@
+// Package analytics aggregates usage statistics for parsed GraphQL operations
+package analytics
+
+import (
+	"log"
+	"testing"
+	"time"
+)
+
+func TestDeduplicator(t *testing.T) {
+	log.Println("Starting TestDeduplicator")
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	d := NewDeduplicator(clock, time.Minute)
+
+	for i := 0; i < 5; i++ {
+		if first := d.Observe("abc", "web"); first != (i == 0) {
+			t.Errorf("Observe %d: expected first = %v", i, i == 0)
+		}
+	}
+	if !d.Observe("abc", "ios") {
+		t.Errorf("Expected a different client to be processed")
+	}
+	if !d.Observe("def", "web") {
+		t.Errorf("Expected a different fingerprint to be processed")
+	}
+
+	records := d.Records()
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(records))
+	}
+	if got := records[0]; got.Fingerprint != "abc" || got.Client != "ios" || got.Count != 1 {
+		t.Errorf("Unexpected record %+v", got)
+	}
+	if got := records[1]; got.Fingerprint != "abc" || got.Client != "web" || got.Count != 5 {
+		t.Errorf("Unexpected record %+v", got)
+	}
+
+	clock.Advance(time.Minute)
+	if !d.Observe("abc", "web") {
+		t.Errorf("Expected the event to be processed again after the window")
+	}
+	records = d.Records()
+	if len(records) != 1 || records[0].Count != 1 {
+		t.Errorf("Expected expired records to be pruned, got %+v", records)
+	}
+}
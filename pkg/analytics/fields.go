@@ -0,0 +1,139 @@
+// Package analytics aggregates usage statistics for parsed GraphQL operations
+package analytics
+
+import (
+	"maps"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tom/graphqlinsights/pkg/parser"
+)
+
+// FieldCount is the number of times a single field was selected
+type FieldCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// FieldStats counts how often each field name is selected across all recorded
+// operations, how many operations of each type were recorded, and the size of
+// the recorded query text. It is safe for concurrent use by multiple workers.
+type FieldStats struct {
+	mu             sync.Mutex
+	counts         map[string]int
+	operationTypes map[string]int // Keyed by query, mutation, or subscription
+	queryBytes     int64          // Total size of the queries passed to RecordQuerySize
+	queries        int            // Number of queries passed to RecordQuerySize
+}
+
+// NewFieldStats creates an empty field usage aggregator
+func NewFieldStats() *FieldStats {
+	return &FieldStats{counts: make(map[string]int), operationTypes: make(map[string]int)}
+}
+
+// Record adds one use of every field selected anywhere in the tree rooted at n,
+// and one operation of its type for every operation in it. Fields inside a
+// fragment definition are counted once per recorded document, not once per spread.
+func (s *FieldStats) Record(n *parser.Node) {
+	local := make(map[string]int)
+	operationTypes := make(map[string]int)
+	parser.Walk(n, func(node *parser.Node) bool {
+		if node.Type == parser.NodeField {
+			local[node.Name]++
+		} else if node.IsOperation() {
+			operationTypes[node.OperationType]++
+		}
+		return true
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, count := range local {
+		s.counts[name] += count
+	}
+	for operationType, count := range operationTypes {
+		s.operationTypes[operationType] += count
+	}
+}
+
+// Snapshot returns a copy of the current per-field counts
+func (s *FieldStats) Snapshot() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.counts)
+}
+
+// CaseVariants groups the recorded field names that differ only in letter case,
+// such as name and Name, which usually points at inconsistent client code. The
+// result is keyed by the lowercased name and each group is sorted; names with a
+// single casing are left out.
+func CaseVariants(stats *FieldStats) map[string][]string {
+	groups := make(map[string][]string)
+	for name := range stats.Snapshot() {
+		key := strings.ToLower(name)
+		groups[key] = append(groups[key], name)
+	}
+	for key, names := range groups {
+		if len(names) < 2 {
+			delete(groups, key)
+			continue
+		}
+		sort.Strings(names)
+	}
+	return groups
+}
+
+// OperationTypeCounts returns a copy of the number of recorded operations keyed
+// by operation type: query, mutation, or subscription
+func (s *FieldStats) OperationTypeCounts() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.operationTypes)
+}
+
+// RecordQuerySize adds one query of the given size in bytes
+func (s *FieldStats) RecordQuerySize(bytes int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queryBytes += int64(bytes)
+	s.queries++
+}
+
+// TotalQueryBytes returns the total size in bytes of all queries recorded with RecordQuerySize
+func (s *FieldStats) TotalQueryBytes() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queryBytes
+}
+
+// AverageQuerySize returns the mean size in bytes of the queries recorded with
+// RecordQuerySize, or zero if none were recorded
+func (s *FieldStats) AverageQuerySize() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.queries == 0 {
+		return 0
+	}
+	return float64(s.queryBytes) / float64(s.queries)
+}
+
+// TopN returns the n most-used fields ordered by descending count, breaking ties
+// by name so that the order is deterministic. It returns every field when fewer
+// than n have been recorded, and none when n is not positive.
+func (s *FieldStats) TopN(n int) []FieldCount {
+	s.mu.Lock()
+	top := make([]FieldCount, 0, len(s.counts))
+	for name, count := range s.counts {
+		top = append(top, FieldCount{Name: name, Count: count})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Count != top[j].Count {
+			return top[i].Count > top[j].Count
+		}
+		return top[i].Name < top[j].Name
+	})
+	return top[:min(max(n, 0), len(top))]
+}
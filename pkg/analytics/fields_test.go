@@ -0,0 +1,188 @@
+// Package analytics aggregates usage statistics for parsed GraphQL operations
+package analytics
+
+import (
+	"log"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/tom/graphqlinsights/pkg/parser"
+)
+
+func TestFieldStatsRecord(t *testing.T) {
+	log.Println("Starting TestFieldStatsRecord")
+	document, err := parser.NewParser(`
+		query Q { user(id: 1) { name ...Contact ... on Admin { name } } }
+		fragment Contact on User { email }
+	`).ParseDocument()
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+
+	stats := NewFieldStats()
+	stats.Record(document)
+
+	want := map[string]int{"user": 1, "name": 2, "email": 1}
+	if got := stats.Snapshot(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestFieldStatsConcurrentRecord(t *testing.T) {
+	log.Println("Starting TestFieldStatsConcurrentRecord")
+	query, err := parser.NewParser(`{ viewer { id name } }`).ParseQuery()
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+
+	stats := NewFieldStats()
+	const goroutines, perGoroutine = 8, 250
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perGoroutine; j++ {
+				stats.Record(query)
+				stats.Snapshot()
+			}
+		}()
+	}
+	wg.Wait()
+
+	total := goroutines * perGoroutine
+	want := map[string]int{"viewer": total, "id": total, "name": total}
+	if got := stats.Snapshot(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestFieldStatsOperationTypeCounts(t *testing.T) {
+	log.Println("Starting TestFieldStatsOperationTypeCounts")
+	inputs := []string{
+		`{ viewer { id } }`,
+		`query Feed { posts { id } } mutation Like { like { ok } } fragment F on Post { id }`,
+		`subscription OnPost { postAdded { id } }`,
+	}
+	documents := make([]*parser.Node, len(inputs))
+	for i, input := range inputs {
+		document, err := parser.NewParser(input).ParseDocument()
+		if err != nil {
+			t.Fatalf("Unexpected parse error: %s", err)
+		}
+		documents[i] = document
+	}
+
+	stats := NewFieldStats()
+	const goroutines, perGoroutine = 8, 100
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perGoroutine; j++ {
+				for _, document := range documents {
+					stats.Record(document)
+				}
+				stats.OperationTypeCounts()
+			}
+		}()
+	}
+	wg.Wait()
+
+	total := goroutines * perGoroutine
+	want := map[string]int{"query": 2 * total, "mutation": total, "subscription": total}
+	if got := stats.OperationTypeCounts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestCaseVariants(t *testing.T) {
+	log.Println("Starting TestCaseVariants")
+	stats := NewFieldStats()
+	for _, query := range []string{
+		`{ user { name email } }`,
+		`{ User { Name NAME id } }`,
+		`{ viewer { id } }`,
+	} {
+		document, err := parser.NewParser(query).ParseDocument()
+		if err != nil {
+			t.Fatalf("Unexpected parse error: %s", err)
+		}
+		stats.Record(document)
+	}
+
+	want := map[string][]string{
+		"name": {"NAME", "Name", "name"},
+		"user": {"User", "user"},
+	}
+	if got := CaseVariants(stats); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if got := CaseVariants(NewFieldStats()); len(got) != 0 {
+		t.Errorf("Expected no variants for empty stats, got %v", got)
+	}
+}
+
+func TestFieldStatsQuerySize(t *testing.T) {
+	log.Println("Starting TestFieldStatsQuerySize")
+	stats := NewFieldStats()
+	if got := stats.AverageQuerySize(); got != 0 {
+		t.Errorf("Expected an average of 0 before any query, got %v", got)
+	}
+
+	sizes := []int{10, 20, 35, 55}
+	const goroutines = 8
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, size := range sizes {
+				stats.RecordQuerySize(size)
+				stats.AverageQuerySize()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := stats.TotalQueryBytes(); got != goroutines*120 {
+		t.Errorf("Expected %d bytes, got %d", goroutines*120, got)
+	}
+	if got := stats.AverageQuerySize(); got != 30 {
+		t.Errorf("Expected an average of 30 bytes, got %v", got)
+	}
+}
+
+func TestFieldStatsTopN(t *testing.T) {
+	log.Println("Starting TestFieldStatsTopN")
+	stats := NewFieldStats()
+	for _, input := range []string{`{ a b c e }`, `{ b c e }`, `{ c d }`, `{ f }`} {
+		query, err := parser.NewParser(input).ParseQuery()
+		if err != nil {
+			t.Fatalf("Unexpected parse error: %s", err)
+		}
+		stats.Record(query)
+	}
+
+	tests := []struct {
+		name string
+		n    int
+		want []FieldCount
+	}{
+		{name: "Truncated", n: 3, want: []FieldCount{{Name: "c", Count: 3}, {Name: "b", Count: 2}, {Name: "e", Count: 2}}},
+		{name: "Ties broken by name", n: 6, want: []FieldCount{{Name: "c", Count: 3}, {Name: "b", Count: 2}, {Name: "e", Count: 2}, {Name: "a", Count: 1}, {Name: "d", Count: 1}, {Name: "f", Count: 1}}},
+		{name: "More than recorded", n: 10, want: []FieldCount{{Name: "c", Count: 3}, {Name: "b", Count: 2}, {Name: "e", Count: 2}, {Name: "a", Count: 1}, {Name: "d", Count: 1}, {Name: "f", Count: 1}}},
+		{name: "Zero", n: 0, want: []FieldCount{}},
+		{name: "Negative", n: -1, want: []FieldCount{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stats.TopN(tt.n); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
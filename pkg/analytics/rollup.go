@@ -0,0 +1,170 @@
+// Package analytics aggregates usage statistics for parsed GraphQL operations
+package analytics
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock provides the current time so time-based jobs can be driven by tests
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is a Clock backed by time.Now
+type SystemClock struct{}
+
+// Now returns the current wall-clock time
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// RollupRecord summarizes the usage of one operation fingerprint over one rollup window
+type RollupRecord struct {
+	WindowStart     time.Time
+	Fingerprint     string
+	Count           int
+	DistinctClients int
+	AverageDepth    float64
+}
+
+// Store persists rollup records for long-term trend analysis
+type Store interface {
+	SaveRollup(record RollupRecord) error
+}
+
+// MemoryStore is a Store that keeps rollup records in memory
+type MemoryStore struct {
+	mu      sync.Mutex
+	records []RollupRecord
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// SaveRollup appends the record to the store
+func (s *MemoryStore) SaveRollup(record RollupRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+	return nil
+}
+
+// Rollups returns a copy of all records saved so far
+func (s *MemoryStore) Rollups() []RollupRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]RollupRecord(nil), s.records...)
+}
+
+// rollupCounter accumulates usage of a single fingerprint within the current window
+type rollupCounter struct {
+	count      int
+	totalDepth int
+	clients    map[string]bool
+}
+
+// HourlyRollup accumulates per-fingerprint counters and writes them to a Store
+// each time a rollup window (one hour by default) ends
+type HourlyRollup struct {
+	mu          sync.Mutex
+	store       Store
+	clock       Clock
+	interval    time.Duration
+	windowStart time.Time
+	counters    map[string]*rollupCounter
+}
+
+// NewHourlyRollup creates a rollup writing to store. A non-positive interval defaults to one hour.
+func NewHourlyRollup(store Store, clock Clock, interval time.Duration) *HourlyRollup {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &HourlyRollup{
+		store:       store,
+		clock:       clock,
+		interval:    interval,
+		windowStart: clock.Now().Truncate(interval),
+		counters:    make(map[string]*rollupCounter),
+	}
+}
+
+// Record counts one occurrence of the operation with the given fingerprint
+func (r *HourlyRollup) Record(fingerprint, client string, depth int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.rotate(); err != nil {
+		log.Printf("Could not write rollup: %s", err)
+	}
+
+	counter, ok := r.counters[fingerprint]
+	if !ok {
+		counter = &rollupCounter{clients: make(map[string]bool)}
+		r.counters[fingerprint] = counter
+	}
+	counter.count++
+	counter.totalDepth += depth
+	counter.clients[client] = true
+}
+
+// Flush writes the current window's records to the store if the window has ended
+func (r *HourlyRollup) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rotate()
+}
+
+// Run periodically flushes completed windows until the context is cancelled
+func (r *HourlyRollup) Run(ctx context.Context) {
+	ticker := time.NewTicker(min(r.interval, time.Minute))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Flush(); err != nil {
+				log.Printf("Could not write rollup: %s", err)
+			}
+		}
+	}
+}
+
+// rotate writes and resets the counters once the clock has moved past the current window.
+// The caller must hold r.mu.
+func (r *HourlyRollup) rotate() error {
+	now := r.clock.Now()
+	if now.Before(r.windowStart.Add(r.interval)) {
+		return nil
+	}
+
+	fingerprints := make([]string, 0, len(r.counters))
+	for fingerprint := range r.counters {
+		fingerprints = append(fingerprints, fingerprint)
+	}
+	sort.Strings(fingerprints)
+
+	var firstErr error
+	for _, fingerprint := range fingerprints {
+		counter := r.counters[fingerprint]
+		record := RollupRecord{
+			WindowStart:     r.windowStart,
+			Fingerprint:     fingerprint,
+			Count:           counter.count,
+			DistinctClients: len(counter.clients),
+			AverageDepth:    float64(counter.totalDepth) / float64(counter.count),
+		}
+		if err := r.store.SaveRollup(record); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	r.counters = make(map[string]*rollupCounter)
+	r.windowStart = now.Truncate(r.interval)
+	return firstErr
+}
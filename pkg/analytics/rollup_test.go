@@ -0,0 +1,91 @@
+// Package analytics aggregates usage statistics for parsed GraphQL operations
+package analytics
+
+import (
+	"log"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock whose time only moves when advanced by the test
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestHourlyRollup(t *testing.T) {
+	log.Println("Starting TestHourlyRollup")
+	clock := &fakeClock{now: time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	rollup := NewHourlyRollup(store, clock, 0)
+
+	rollup.Record("abc", "web", 2)
+	rollup.Record("abc", "ios", 4)
+	rollup.Record("abc", "web", 3)
+	rollup.Record("def", "web", 1)
+
+	if err := rollup.Flush(); err != nil {
+		t.Fatalf("Unexpected flush error: %s", err)
+	}
+	if got := len(store.Rollups()); got != 0 {
+		t.Fatalf("Expected no rollups before the hour ends, got %d", got)
+	}
+
+	clock.Advance(31 * time.Minute)
+	if err := rollup.Flush(); err != nil {
+		t.Fatalf("Unexpected flush error: %s", err)
+	}
+
+	records := store.Rollups()
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 rollup records, got %d: %+v", len(records), records)
+	}
+	want := RollupRecord{
+		WindowStart:     time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		Fingerprint:     "abc",
+		Count:           3,
+		DistinctClients: 2,
+		AverageDepth:    3,
+	}
+	if records[0] != want {
+		t.Errorf("Expected %+v, got %+v", want, records[0])
+	}
+
+	// Counters are reset, so the next window starts empty
+	clock.Advance(time.Hour)
+	if err := rollup.Flush(); err != nil {
+		t.Fatalf("Unexpected flush error: %s", err)
+	}
+	if got := len(store.Rollups()); got != 2 {
+		t.Errorf("Expected counters to be reset after rollup, got %d records", got)
+	}
+}
+
+func TestHourlyRollupRecordAfterBoundary(t *testing.T) {
+	log.Println("Starting TestHourlyRollupRecordAfterBoundary")
+	clock := &fakeClock{now: time.Date(2024, 5, 1, 10, 59, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	rollup := NewHourlyRollup(store, clock, time.Hour)
+
+	rollup.Record("abc", "web", 1)
+	clock.Advance(2 * time.Minute)
+	rollup.Record("abc", "web", 1)
+
+	records := store.Rollups()
+	if len(records) != 1 || records[0].Count != 1 {
+		t.Fatalf("Expected the first window to be written with count 1, got %+v", records)
+	}
+}
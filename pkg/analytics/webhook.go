@@ -0,0 +1,143 @@
+// Package analytics aggregates usage statistics for parsed GraphQL operations
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Thresholds configures the limits above which an operation triggers an alert.
+// A zero limit disables that check.
+type Thresholds struct {
+	MaxDepth      int
+	MaxComplexity int
+}
+
+// Check returns a description of every threshold the operation exceeds
+func (t Thresholds) Check(depth, complexity int) []string {
+	var reasons []string
+	if t.MaxDepth > 0 && depth > t.MaxDepth {
+		reasons = append(reasons, fmt.Sprintf("depth %d exceeds limit of %d", depth, t.MaxDepth))
+	}
+	if t.MaxComplexity > 0 && complexity > t.MaxComplexity {
+		reasons = append(reasons, fmt.Sprintf("complexity %d exceeds limit of %d", complexity, t.MaxComplexity))
+	}
+	return reasons
+}
+
+// Alert is the JSON payload posted to the webhook for an operation over the thresholds
+type Alert struct {
+	OperationName string   `json:"operation_name"`
+	ClientName    string   `json:"client_name"`
+	Depth         int      `json:"depth"`
+	Complexity    int      `json:"complexity"`
+	Reasons       []string `json:"reasons"`
+}
+
+// WebhookConfig configures delivery of alerts to a webhook
+type WebhookConfig struct {
+	URL        string
+	Timeout    time.Duration // Timeout per delivery attempt, 5s if zero
+	Retries    int           // Attempts made after the first one fails
+	RetryDelay time.Duration // Delay before each retry, multiplied by the attempt number
+	QueueSize  int           // Alerts buffered before new ones are dropped, 100 if zero
+	Client     *http.Client  // HTTP client used for delivery, http.DefaultClient if nil
+}
+
+// WebhookNotifier delivers alerts to a webhook in the background so that a slow
+// or failing webhook never blocks the caller
+type WebhookNotifier struct {
+	config WebhookConfig
+	alerts chan Alert
+	wg     sync.WaitGroup
+}
+
+// NewWebhookNotifier creates a notifier and starts its delivery goroutine
+func NewWebhookNotifier(config WebhookConfig) *WebhookNotifier {
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Second
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = 100
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+
+	w := &WebhookNotifier{config: config, alerts: make(chan Alert, config.QueueSize)}
+	w.wg.Add(1)
+	go w.run()
+	return w
+}
+
+// Notify queues an alert for delivery without blocking. It reports false if the
+// queue is full and the alert was dropped.
+func (w *WebhookNotifier) Notify(alert Alert) bool {
+	select {
+	case w.alerts <- alert:
+		return true
+	default:
+		log.Printf("Webhook queue full, dropping alert for %s", alert.OperationName)
+		return false
+	}
+}
+
+// Close stops accepting alerts and waits for queued alerts to be delivered
+func (w *WebhookNotifier) Close() {
+	close(w.alerts)
+	w.wg.Wait()
+}
+
+// run delivers queued alerts until the notifier is closed
+func (w *WebhookNotifier) run() {
+	defer w.wg.Done()
+	for alert := range w.alerts {
+		if err := w.deliver(alert); err != nil {
+			log.Printf("Could not deliver webhook alert for %s: %s", alert.OperationName, err)
+		}
+	}
+}
+
+// deliver posts an alert, retrying failed attempts
+func (w *WebhookNotifier) deliver(alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		err = w.post(body)
+		if err == nil || attempt >= w.config.Retries {
+			return err
+		}
+		time.Sleep(w.config.RetryDelay * time.Duration(attempt+1))
+	}
+}
+
+// post makes a single delivery attempt
+func (w *WebhookNotifier) post(body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), w.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.config.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+// Package analytics aggregates usage statistics for parsed GraphQL operations
+package analytics
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestThresholdsCheck(t *testing.T) {
+	log.Println("Starting TestThresholdsCheck")
+	thresholds := Thresholds{MaxDepth: 5, MaxComplexity: 100}
+
+	if reasons := thresholds.Check(5, 100); len(reasons) != 0 {
+		t.Errorf("Expected no reasons at the limits, got %v", reasons)
+	}
+	if reasons := thresholds.Check(6, 101); len(reasons) != 2 {
+		t.Errorf("Expected both limits to be reported, got %v", reasons)
+	}
+	if reasons := (Thresholds{}).Check(1000, 1000); len(reasons) != 0 {
+		t.Errorf("Expected zero thresholds to be disabled, got %v", reasons)
+	}
+}
+
+func TestWebhookNotifierDeliversAlert(t *testing.T) {
+	log.Println("Starting TestWebhookNotifierDeliversAlert")
+	var (
+		mu       sync.Mutex
+		received []Alert
+		attempts int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		// Fail the first attempt to exercise retries
+		if attempts == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var alert Alert
+		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+			t.Errorf("Could not decode alert: %s", err)
+		}
+		received = append(received, alert)
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier(WebhookConfig{URL: server.URL, Retries: 2, Client: server.Client()})
+	thresholds := Thresholds{MaxDepth: 3}
+	alert := Alert{
+		OperationName: "DeepQuery",
+		ClientName:    "web",
+		Depth:         7,
+		Reasons:       thresholds.Check(7, 0),
+	}
+	if !notifier.Notify(alert) {
+		t.Fatalf("Expected alert to be queued")
+	}
+	notifier.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != 2 {
+		t.Errorf("Expected 2 delivery attempts, got %d", attempts)
+	}
+	if len(received) != 1 || !reflect.DeepEqual(received[0], alert) {
+		t.Errorf("Expected webhook to receive %+v, got %+v", alert, received)
+	}
+}
+
+func TestWebhookNotifierDoesNotBlock(t *testing.T) {
+	log.Println("Starting TestWebhookNotifierDoesNotBlock")
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier(WebhookConfig{URL: server.URL, QueueSize: 1, Client: server.Client()})
+	start := time.Now()
+	for range 5 {
+		notifier.Notify(Alert{OperationName: "Slow"})
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected Notify not to block on a hanging webhook, took %s", elapsed)
+	}
+	close(release)
+	notifier.Close()
+}
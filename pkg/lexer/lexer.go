@@ -2,7 +2,13 @@
 package lexer
 
 import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
 	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
 // TokenType represents the type of a token in the GraphQL query
@@ -10,21 +16,35 @@ type TokenType string
 
 // Token types for GraphQL query lexing
 const (
-	TokenBraceL TokenType = "{"
-	TokenBraceR TokenType = "}"
-	TokenParenL TokenType = "("
-	TokenParenR TokenType = ")"
-	TokenColon  TokenType = ":"
-	TokenAt     TokenType = "@" // Token for @ symbol used in directives
-	TokenString TokenType = "STRING"
-	TokenIdent  TokenType = "IDENT"
-	TokenEOF    TokenType = "EOF"
+	TokenBraceL   TokenType = "{"
+	TokenBraceR   TokenType = "}"
+	TokenParenL   TokenType = "("
+	TokenParenR   TokenType = ")"
+	TokenBracketL TokenType = "["
+	TokenBracketR TokenType = "]"
+	TokenColon    TokenType = ":"
+	TokenAt       TokenType = "@"   // Token for @ symbol used in directives
+	TokenDollar   TokenType = "$"   // Token for $ before a variable name; the name follows as a TokenIdent
+	TokenBang     TokenType = "!"   // Token for ! marking non-null types
+	TokenEquals   TokenType = "="   // Token for = before a variable's default value
+	TokenSpread   TokenType = "..." // Token for ... in fragment spreads and inline fragments
+	TokenString   TokenType = "STRING"
+	TokenInt      TokenType = "INT"
+	TokenFloat    TokenType = "FLOAT"
+	TokenIdent    TokenType = "IDENT"
+	TokenComment  TokenType = "COMMENT" // Token for # line comments, only emitted when requested
+	TokenIllegal  TokenType = "ILLEGAL" // Token for malformed input; the value describes the problem
+	TokenEOF      TokenType = "EOF"
 )
 
 // Token represents a lexical token in the GraphQL query
 type Token struct {
-	Type  TokenType
-	Value string
+	Type   TokenType
+	Value  string
+	Line   int  // 1-based line of the first character of the token
+	Column int  // 1-based column of the first character of the token
+	Offset int  // Byte offset of the first character of the token
+	Block  bool // Set on TokenString when the value came from a """block string"""
 }
 
 // Helper functions for character classification
@@ -36,74 +56,541 @@ func isDigit(ch rune) bool {
 	return unicode.IsDigit(ch)
 }
 
+// isNumberDigit reports whether ch is an ASCII digit, the only digits allowed in numeric literals
+func isNumberDigit(ch rune) bool {
+	return ch >= '0' && ch <= '9'
+}
+
 // Lexer represents a lexical analyzer for GraphQL queries
 type Lexer struct {
-	input       string
-	position    int
-	currentChar rune
+	input        string        // Input read directly when lexing a string
+	reader       io.RuneReader // Source of the input when lexing a reader, otherwise nil
+	readErr      error         // First read error other than io.EOF
+	lookahead    []sourceRune  // Characters read from reader ahead of currentChar
+	position     int           // byte offset of currentChar
+	width        int           // byte width of currentChar
+	currentChar  rune
+	line         int // line of currentChar
+	column       int // column of currentChar
+	startLine    int // line where the token being scanned starts
+	startColumn  int // column where the token being scanned starts
+	startOffset  int // byte offset where the token being scanned starts
+	lastOffset   int // byte offset of the token most recently returned by NextToken
+	emitComments bool
+	eof          bool  // Whether the input is exhausted; currentChar is then zero
+	peeked       Token // Token read ahead by Peek, valid when hasPeeked is set
+	hasPeeked    bool
+	capturing    bool            // Whether consumed characters are being captured
+	captureStart int             // byte offset where capturing started
+	captured     strings.Builder // Captured characters when lexing a reader; string input is sliced instead
+}
+
+// sourceRune is a character read from the input together with its encoded width
+type sourceRune struct {
+	char  rune
+	width int
 }
 
-// NewLexer creates a new lexer for the given input string
+// NewLexer creates a new lexer that reads the given input string directly.
+// Comments are skipped like whitespace.
 func NewLexer(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
+	l.readChar()
+	return l
+}
+
+// NewLexerReader creates a new lexer that consumes input incrementally from r
+// instead of requiring the whole document in memory.
+// Comments are skipped like whitespace.
+func NewLexerReader(r io.Reader) *Lexer {
+	if runeReader, ok := r.(io.RuneReader); ok {
+		return newLexer(runeReader)
+	}
+	return newLexer(bufio.NewReader(r))
+}
+
+// newLexer creates a lexer reading from the given source
+func newLexer(reader io.RuneReader) *Lexer {
+	l := &Lexer{reader: reader, line: 1}
 	l.readChar()
 	return l
 }
 
-// readChar reads the next character and advances the position in the input string
+// Reset discards all state and prepares the lexer to tokenize input, reusing
+// its buffers where possible. Whether comments are emitted is kept.
+func (l *Lexer) Reset(input string) {
+	*l = Lexer{
+		input:        input,
+		lookahead:    l.lookahead[:0],
+		line:         1,
+		emitComments: l.emitComments,
+	}
+	l.readChar()
+}
+
+// NewLexerWithComments creates a new lexer that returns # line comments as TokenComment
+// tokens instead of skipping them, so tooling can preserve them
+func NewLexerWithComments(input string) *Lexer {
+	l := NewLexer(input)
+	l.emitComments = true
+	return l
+}
+
+// readChar reads the next character and advances the position in the input
 func (l *Lexer) readChar() {
-	if l.position >= len(l.input) {
-		l.currentChar = 0
-	} else {
-		l.currentChar = rune(l.input[l.position])
+	if l.capturing && l.reader != nil && !l.eof {
+		l.captured.WriteRune(l.currentChar)
+	}
+	// \n, \r and \r\n each end a line; the \r of a \r\n pair does not count on its own
+	if l.currentChar == '\n' || (l.currentChar == '\r' && l.peekChar(0).char != '\n') {
+		l.line++
+		l.column = 0
+	}
+	l.column++
+
+	next := l.peekChar(0)
+	l.position += l.width
+	if l.reader != nil {
+		// Shift rather than reslice so the buffer keeps its capacity
+		l.lookahead = l.lookahead[:copy(l.lookahead, l.lookahead[1:])]
 	}
-	l.position++
+	l.currentChar, l.width = next.char, next.width
+	// Only the end of the input has no width; a NUL character in it has a width of one
+	l.eof = next.width == 0
+}
+
+// peekChar returns the character n positions after currentChar without consuming
+// anything; peekChar(0) is the character readChar would move to next.
+// The end of the input is reported as a zero character with zero width.
+func (l *Lexer) peekChar(n int) sourceRune {
+	if l.reader == nil {
+		offset := l.position + l.width
+		for ; n > 0 && offset < len(l.input); n-- {
+			_, width := utf8.DecodeRuneInString(l.input[offset:])
+			offset += width
+		}
+		if offset >= len(l.input) {
+			return sourceRune{}
+		}
+		char, width := utf8.DecodeRuneInString(l.input[offset:])
+		return sourceRune{char: char, width: width}
+	}
+	for len(l.lookahead) <= n {
+		r, width, err := l.reader.ReadRune()
+		if err != nil {
+			if err != io.EOF && l.readErr == nil {
+				l.readErr = err
+			}
+			r, width = 0, 0
+		}
+		l.lookahead = append(l.lookahead, sourceRune{char: r, width: width})
+	}
+	return l.lookahead[n]
+}
+
+// startCapture begins recording the characters consumed by readChar
+func (l *Lexer) startCapture() {
+	l.captureStart = l.position
+	if l.reader != nil {
+		l.captured.Reset()
+	}
+	l.capturing = true
+}
+
+// stopCapture stops recording and returns the characters consumed since
+// startCapture. For string input this is a slice of the input, which costs no
+// allocation.
+func (l *Lexer) stopCapture() string {
+	l.capturing = false
+	if l.reader == nil {
+		return l.input[l.captureStart:l.position]
+	}
+	return l.captured.String()
 }
 
 // NextToken returns the next token from the input
 func (l *Lexer) NextToken() Token {
-	for unicode.IsSpace(l.currentChar) {
-		l.readChar()
+	tok := l.Peek()
+	l.hasPeeked = false
+	l.lastOffset = tok.Offset
+	return tok
+}
+
+// Position returns the byte offset in the input where the token most recently
+// returned by NextToken starts
+func (l *Lexer) Position() int {
+	return l.lastOffset
+}
+
+// Peek returns the next token without consuming it. The following call to
+// NextToken returns the same token.
+func (l *Lexer) Peek() Token {
+	if !l.hasPeeked {
+		l.peeked = l.scanToken()
+		l.hasPeeked = true
+	}
+	return l.peeked
+}
+
+// Tokenize returns all remaining tokens up to and including TokenEOF.
+// It stops early after the first TokenIllegal, which is included as the last token.
+func (l *Lexer) Tokenize() []Token {
+	var tokens []Token
+	for {
+		tok := l.NextToken()
+		tokens = append(tokens, tok)
+		if tok.Type == TokenEOF || tok.Type == TokenIllegal {
+			return tokens
+		}
+	}
+}
+
+// scanToken reads the next token from the input
+func (l *Lexer) scanToken() Token {
+	for {
+		// Commas are insignificant in GraphQL and are skipped like whitespace
+		for unicode.IsSpace(l.currentChar) || l.currentChar == ',' {
+			l.readChar()
+		}
+		l.startLine, l.startColumn, l.startOffset = l.line, l.column, l.position
+		if l.currentChar != '#' {
+			break
+		}
+		comment := l.readComment()
+		if l.emitComments {
+			return l.token(TokenComment, comment)
+		}
+	}
+
+	if l.eof {
+		if l.readErr != nil {
+			return l.token(TokenIllegal, "could not read input: "+l.readErr.Error())
+		}
+		return l.token(TokenEOF, "")
 	}
 
 	switch l.currentChar {
 	case '{':
 		l.readChar()
-		return Token{TokenBraceL, "{"}
+		return l.token(TokenBraceL, "{")
 	case '}':
 		l.readChar()
-		return Token{TokenBraceR, "}"}
+		return l.token(TokenBraceR, "}")
 	case '(':
 		l.readChar()
-		return Token{TokenParenL, "("}
+		return l.token(TokenParenL, "(")
 	case ')':
 		l.readChar()
-		return Token{TokenParenR, ")"}
+		return l.token(TokenParenR, ")")
+	case '[':
+		l.readChar()
+		return l.token(TokenBracketL, "[")
+	case ']':
+		l.readChar()
+		return l.token(TokenBracketR, "]")
 	case ':':
 		l.readChar()
-		return Token{TokenColon, ":"}
+		return l.token(TokenColon, ":")
 	case '@': // Handle @ symbol for directives
 		l.readChar()
-		return Token{TokenAt, "@"}
-	case '"':
+		return l.token(TokenAt, "@")
+	case '$':
 		l.readChar()
-		start := l.position - 1
-		for l.currentChar != '"' {
+		return l.token(TokenDollar, "$")
+	case '!':
+		l.readChar()
+		return l.token(TokenBang, "!")
+	case '=':
+		l.readChar()
+		return l.token(TokenEquals, "=")
+	case '.':
+		dots := 0
+		for l.currentChar == '.' && dots < 3 {
 			l.readChar()
+			dots++
+		}
+		if dots < 3 {
+			return l.token(TokenIllegal, "expected ... but found "+strings.Repeat(".", dots))
 		}
-		value := l.input[start:l.position]
+		return l.token(TokenSpread, "...")
+	case '"':
 		l.readChar()
-		return Token{TokenString, value}
-	case 0:
-		return Token{TokenEOF, ""}
+		if l.currentChar == '"' {
+			l.readChar()
+			if l.currentChar != '"' {
+				return l.token(TokenString, "")
+			}
+			l.readChar()
+			value, ok := l.readBlockString()
+			if !ok {
+				return l.token(TokenIllegal, "unterminated block string")
+			}
+			tok := l.token(TokenString, value)
+			tok.Block = true
+			return tok
+		}
+		return l.readString()
 	default:
+		if l.currentChar == '-' || isNumberDigit(l.currentChar) {
+			return l.readNumber()
+		}
 		if isLetter(l.currentChar) {
-			start := l.position - 1
+			l.startCapture()
 			for isLetter(l.currentChar) || isDigit(l.currentChar) {
 				l.readChar()
 			}
-			return Token{TokenIdent, l.input[start : l.position-1]}
+			return l.token(TokenIdent, l.stopCapture())
 		}
 	}
-	return Token{TokenEOF, ""}
+	// Consume the unknown character so the rest of the input is not silently dropped
+	char := l.currentChar
+	l.readChar()
+	return l.token(TokenIllegal, fmt.Sprintf("unexpected character %q", char))
+}
+
+// token builds a token of the given type starting at the position recorded for the current scan
+func (l *Lexer) token(t TokenType, value string) Token {
+	return Token{Type: t, Value: value, Line: l.startLine, Column: l.startColumn, Offset: l.startOffset}
+}
+
+// readNumber scans an int or float literal such as 10, -5, 3.14 or 6.022e23
+func (l *Lexer) readNumber() Token {
+	l.startCapture()
+	isFloat := false
+	valid := true
+
+	if l.currentChar == '-' {
+		l.readChar()
+	}
+	if l.currentChar == '0' {
+		l.readChar()
+		// Leading zeros are not allowed
+		valid = !isNumberDigit(l.currentChar)
+	} else {
+		valid = l.readDigits()
+	}
+	if valid && l.currentChar == '.' {
+		isFloat = true
+		l.readChar()
+		valid = l.readDigits()
+	}
+	if valid && (l.currentChar == 'e' || l.currentChar == 'E') {
+		isFloat = true
+		l.readChar()
+		if l.currentChar == '+' || l.currentChar == '-' {
+			l.readChar()
+		}
+		valid = l.readDigits()
+	}
+	// A number must not run straight into a dot or a name, as in 1.2.3 or 12abc
+	if valid && (l.currentChar == '.' || isLetter(l.currentChar)) {
+		valid = false
+	}
+
+	if !valid {
+		for l.currentChar == '.' || l.currentChar == '-' || l.currentChar == '+' ||
+			isLetter(l.currentChar) || isNumberDigit(l.currentChar) {
+			l.readChar()
+		}
+		return l.token(TokenIllegal, "invalid number "+l.stopCapture())
+	}
+	if isFloat {
+		return l.token(TokenFloat, l.stopCapture())
+	}
+	return l.token(TokenInt, l.stopCapture())
+}
+
+// readDigits consumes a run of ASCII digits, reporting whether there was at least one
+func (l *Lexer) readDigits() bool {
+	if !isNumberDigit(l.currentChar) {
+		return false
+	}
+	for isNumberDigit(l.currentChar) {
+		l.readChar()
+	}
+	return true
+}
+
+// escapedChars maps the character following a backslash to the character it encodes
+var escapedChars = map[rune]rune{
+	'"':  '"',
+	'\\': '\\',
+	'/':  '/',
+	'b':  '\b',
+	'f':  '\f',
+	'n':  '\n',
+	'r':  '\r',
+	't':  '\t',
+}
+
+// readString consumes the body of a regular string after its opening quote up to and
+// including the closing quote, decoding escape sequences
+func (l *Lexer) readString() Token {
+	var value strings.Builder
+	for l.currentChar != '"' {
+		if l.eof || l.currentChar == '\n' || l.currentChar == '\r' {
+			return l.token(TokenIllegal, "unterminated string")
+		}
+		if l.currentChar != '\\' {
+			value.WriteRune(l.currentChar)
+			l.readChar()
+			continue
+		}
+
+		l.readChar()
+		if l.eof {
+			return l.token(TokenIllegal, "unterminated string")
+		}
+		if l.currentChar == 'u' {
+			l.readChar()
+			r, ok := l.readUnicodeEscape()
+			if !ok {
+				return l.illegalString("invalid unicode escape sequence")
+			}
+			value.WriteRune(r)
+			continue
+		}
+		escaped, ok := escapedChars[l.currentChar]
+		if !ok {
+			return l.illegalString(fmt.Sprintf("invalid escape sequence \\%c", l.currentChar))
+		}
+		value.WriteRune(escaped)
+		l.readChar()
+	}
+	l.readChar()
+	return l.token(TokenString, value.String())
+}
+
+// readUnicodeEscape decodes the XXXX of a \uXXXX escape, combining UTF-16 surrogate pairs
+func (l *Lexer) readUnicodeEscape() (rune, bool) {
+	r, ok := l.readHex4()
+	if !ok {
+		return 0, false
+	}
+	if !utf16.IsSurrogate(r) {
+		return r, true
+	}
+	// A high surrogate must be followed by an escaped low surrogate
+	if l.currentChar != '\\' || l.peekChar(0).char != 'u' {
+		return 0, false
+	}
+	l.readChar()
+	l.readChar()
+	low, ok := l.readHex4()
+	if !ok {
+		return 0, false
+	}
+	combined := utf16.DecodeRune(r, low)
+	return combined, combined != unicode.ReplacementChar
+}
+
+// readHex4 consumes four hexadecimal digits and returns their value
+func (l *Lexer) readHex4() (rune, bool) {
+	var r rune
+	for range 4 {
+		digit, ok := hexValue(l.currentChar)
+		if !ok {
+			return 0, false
+		}
+		r = r*16 + digit
+		l.readChar()
+	}
+	return r, true
+}
+
+// hexValue returns the numeric value of a hexadecimal digit
+func hexValue(ch rune) (rune, bool) {
+	switch {
+	case ch >= '0' && ch <= '9':
+		return ch - '0', true
+	case ch >= 'a' && ch <= 'f':
+		return ch - 'a' + 10, true
+	case ch >= 'A' && ch <= 'F':
+		return ch - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// illegalString skips the rest of a malformed string and returns an illegal token with the given message
+func (l *Lexer) illegalString(message string) Token {
+	for l.currentChar != '"' && !l.eof && l.currentChar != '\n' && l.currentChar != '\r' {
+		if l.currentChar == '\\' {
+			l.readChar()
+		}
+		l.readChar()
+	}
+	if l.currentChar == '"' {
+		l.readChar()
+	}
+	return l.token(TokenIllegal, message)
+}
+
+// readBlockString consumes the body of a block string after its opening quotes
+// up to and including the closing quotes, and returns the cleaned value.
+// It reports false if the input ends before the closing quotes.
+func (l *Lexer) readBlockString() (string, bool) {
+	var raw strings.Builder
+	for !l.eof {
+		if l.currentChar == '"' && l.peekChar(0).char == '"' && l.peekChar(1).char == '"' {
+			l.readChar()
+			l.readChar()
+			l.readChar()
+			return blockStringValue(raw.String()), true
+		}
+		if l.currentChar == '\\' && l.peekChar(0).char == '"' && l.peekChar(1).char == '"' && l.peekChar(2).char == '"' {
+			raw.WriteString(`"""`)
+			for range 4 {
+				l.readChar()
+			}
+			continue
+		}
+		raw.WriteRune(l.currentChar)
+		l.readChar()
+	}
+	return "", false
+}
+
+// blockStringValue applies the spec's block string rules: common indentation is removed
+// from every line but the first, and leading and trailing blank lines are dropped
+func blockStringValue(raw string) string {
+	raw = strings.ReplaceAll(raw, "\r\n", "\n")
+	lines := strings.Split(strings.ReplaceAll(raw, "\r", "\n"), "\n")
+
+	commonIndent := -1
+	for _, line := range lines[1:] {
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < len(line) && (commonIndent == -1 || indent < commonIndent) {
+			commonIndent = indent
+		}
+	}
+	if commonIndent > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) >= commonIndent {
+				lines[i] = lines[i][commonIndent:]
+			} else {
+				lines[i] = ""
+			}
+		}
+	}
+
+	isBlank := func(line string) bool {
+		return strings.TrimLeft(line, " \t") == ""
+	}
+	for len(lines) > 0 && isBlank(lines[0]) {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && isBlank(lines[len(lines)-1]) {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// readComment consumes a # comment up to the end of the line and returns its text without the #
+func (l *Lexer) readComment() string {
+	l.readChar()
+	l.startCapture()
+	for l.currentChar != '\n' && l.currentChar != '\r' && !l.eof {
+		l.readChar()
+	}
+	return l.stopCapture()
 }
@@ -0,0 +1,781 @@
+// Package lexer provides tokenization for GraphQL queries
+package lexer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// collectTokens reads tokens from the lexer up to and including TokenEOF
+func collectTokens(l *Lexer) []Token {
+	var tokens []Token
+	for {
+		tok := l.NextToken()
+		tokens = append(tokens, tok)
+		if tok.Type == TokenEOF {
+			return tokens
+		}
+	}
+}
+
+// assertTokens compares token types and values, ignoring any other token fields
+func assertTokens(t *testing.T, got, want []Token) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d tokens, got %d: %+v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i].Type != want[i].Type || got[i].Value != want[i].Value {
+			t.Errorf("Token[%d]: expected %s %q, got %s %q", i, want[i].Type, want[i].Value, got[i].Type, got[i].Value)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	log.Println("Starting TestComments")
+	input := "# leading\nquery X { a # trailing\n b(c: \"#not a comment\") }"
+	tests := []struct {
+		name  string
+		lexer *Lexer
+		want  []Token
+	}{
+		{
+			name:  "Comments skipped by default",
+			lexer: NewLexer(input),
+			want: []Token{
+				{Type: TokenIdent, Value: "query"},
+				{Type: TokenIdent, Value: "X"},
+				{Type: TokenBraceL, Value: "{"},
+				{Type: TokenIdent, Value: "a"},
+				{Type: TokenIdent, Value: "b"},
+				{Type: TokenParenL, Value: "("},
+				{Type: TokenIdent, Value: "c"},
+				{Type: TokenColon, Value: ":"},
+				{Type: TokenString, Value: "#not a comment"},
+				{Type: TokenParenR, Value: ")"},
+				{Type: TokenBraceR, Value: "}"},
+				{Type: TokenEOF, Value: ""},
+			},
+		},
+		{
+			name:  "Comments emitted when requested",
+			lexer: NewLexerWithComments(input),
+			want: []Token{
+				{Type: TokenComment, Value: " leading"},
+				{Type: TokenIdent, Value: "query"},
+				{Type: TokenIdent, Value: "X"},
+				{Type: TokenBraceL, Value: "{"},
+				{Type: TokenIdent, Value: "a"},
+				{Type: TokenComment, Value: " trailing"},
+				{Type: TokenIdent, Value: "b"},
+				{Type: TokenParenL, Value: "("},
+				{Type: TokenIdent, Value: "c"},
+				{Type: TokenColon, Value: ":"},
+				{Type: TokenString, Value: "#not a comment"},
+				{Type: TokenParenR, Value: ")"},
+				{Type: TokenBraceR, Value: "}"},
+				{Type: TokenEOF, Value: ""},
+			},
+		},
+		{
+			name:  "Comment at end of input",
+			lexer: NewLexerWithComments("a # done"),
+			want: []Token{
+				{Type: TokenIdent, Value: "a"},
+				{Type: TokenComment, Value: " done"},
+				{Type: TokenEOF, Value: ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertTokens(t, collectTokens(tt.lexer), tt.want)
+		})
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	log.Println("Starting TestTokenPositions")
+	input := "query X {\n  user {\n    name\n  }\n}"
+	want := []Token{
+		{Type: TokenIdent, Value: "query", Line: 1, Column: 1, Offset: 0},
+		{Type: TokenIdent, Value: "X", Line: 1, Column: 7, Offset: 6},
+		{Type: TokenBraceL, Value: "{", Line: 1, Column: 9, Offset: 8},
+		{Type: TokenIdent, Value: "user", Line: 2, Column: 3, Offset: 12},
+		{Type: TokenBraceL, Value: "{", Line: 2, Column: 8, Offset: 17},
+		{Type: TokenIdent, Value: "name", Line: 3, Column: 5, Offset: 23},
+		{Type: TokenBraceR, Value: "}", Line: 4, Column: 3, Offset: 30},
+		{Type: TokenBraceR, Value: "}", Line: 5, Column: 1, Offset: 32},
+		{Type: TokenEOF, Value: "", Line: 5, Column: 2, Offset: 33},
+	}
+
+	got := collectTokens(NewLexer(input))
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d tokens, got %d: %+v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Token[%d]: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestLineTerminators(t *testing.T) {
+	log.Println("Starting TestLineTerminators")
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "LF", input: "query X {\n  user # note\n  {\n    name\n}"},
+		{name: "CRLF", input: "query X {\r\n  user # note\r\n  {\r\n    name\r\n}"},
+		{name: "CR", input: "query X {\r  user # note\r  {\r    name\r}"},
+		{name: "Mixed", input: "query X {\r\n  user # note\r  {\n    name\r\n}"},
+	}
+	want := []struct {
+		value        string
+		line, column int
+	}{
+		{"query", 1, 1}, {"X", 1, 7}, {"{", 1, 9},
+		{"user", 2, 3}, {"{", 3, 3}, {"name", 4, 5}, {"}", 5, 1}, {"", 5, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := collectTokens(NewLexer(tt.input))
+			if len(got) != len(want) {
+				t.Fatalf("Expected %d tokens, got %d: %+v", len(want), len(got), got)
+			}
+			for i, w := range want {
+				if got[i].Value != w.value || got[i].Line != w.line || got[i].Column != w.column {
+					t.Errorf("Token[%d]: expected %q at %d:%d, got %q at %d:%d", i, w.value, w.line, w.column, got[i].Value, got[i].Line, got[i].Column)
+				}
+			}
+		})
+	}
+}
+
+func TestBlockStrings(t *testing.T) {
+	log.Println("Starting TestBlockStrings")
+	tests := []struct {
+		name  string
+		input string
+		want  string
+		block bool
+	}{
+		{name: "Regular string", input: `"hello"`, want: "hello"},
+		{name: "Empty string", input: `""`, want: ""},
+		{name: "Single line block", input: `"""hello"""`, want: "hello", block: true},
+		{name: "Unescaped quotes", input: `"""say "hi" now"""`, want: `say "hi" now`, block: true},
+		{name: "Escaped triple quote", input: `"""a \""" b"""`, want: `a """ b`, block: true},
+		{
+			name:  "Indentation stripped",
+			input: "\"\"\"\n    Fetches a user.\n\n      Indented line\n    Last line\n  \"\"\"",
+			want:  "Fetches a user.\n\n  Indented line\nLast line",
+			block: true,
+		},
+		{
+			name:  "First line keeps its indentation",
+			input: "\"\"\"  first\n    second\"\"\"",
+			want:  "  first\nsecond",
+			block: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLexer(tt.input)
+			tok := l.NextToken()
+			if tok.Type != TokenString || tok.Value != tt.want || tok.Block != tt.block {
+				t.Errorf("Expected STRING %q (block=%v), got %s %q (block=%v)", tt.want, tt.block, tok.Type, tok.Value, tok.Block)
+			}
+			if next := l.NextToken(); next.Type != TokenEOF {
+				t.Errorf("Expected EOF after string, got %+v", next)
+			}
+		})
+	}
+}
+
+func TestUnterminatedStrings(t *testing.T) {
+	log.Println("Starting TestUnterminatedStrings")
+	tests := []struct {
+		name  string
+		input string
+		want  []Token
+	}{
+		{
+			name:  "Unterminated string",
+			input: `query X { a(b: "unterminated) }`,
+			want: []Token{
+				{Type: TokenIdent, Value: "query"},
+				{Type: TokenIdent, Value: "X"},
+				{Type: TokenBraceL, Value: "{"},
+				{Type: TokenIdent, Value: "a"},
+				{Type: TokenParenL, Value: "("},
+				{Type: TokenIdent, Value: "b"},
+				{Type: TokenColon, Value: ":"},
+				{Type: TokenIllegal, Value: "unterminated string"},
+			},
+		},
+		{
+			name:  "String broken by a newline",
+			input: "\"abc\ndef\"",
+			want:  []Token{{Type: TokenIllegal, Value: "unterminated string"}},
+		},
+		{
+			name:  "Unterminated block string",
+			input: `"""abc`,
+			want:  []Token{{Type: TokenIllegal, Value: "unterminated block string"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLexer(tt.input)
+			var got []Token
+			for range tt.want {
+				got = append(got, l.NextToken())
+			}
+			assertTokens(t, got, tt.want)
+		})
+	}
+}
+
+func TestNonASCIIInput(t *testing.T) {
+	log.Println("Starting TestNonASCIIInput")
+	input := `{ café(name: "café 🎉") }`
+	want := []Token{
+		{Type: TokenBraceL, Value: "{", Line: 1, Column: 1, Offset: 0},
+		{Type: TokenIdent, Value: "café", Line: 1, Column: 3, Offset: 2},
+		{Type: TokenParenL, Value: "(", Line: 1, Column: 7, Offset: 7},
+		{Type: TokenIdent, Value: "name", Line: 1, Column: 8, Offset: 8},
+		{Type: TokenColon, Value: ":", Line: 1, Column: 12, Offset: 12},
+		{Type: TokenString, Value: "café 🎉", Line: 1, Column: 14, Offset: 14},
+		{Type: TokenParenR, Value: ")", Line: 1, Column: 22, Offset: 26},
+		{Type: TokenBraceR, Value: "}", Line: 1, Column: 24, Offset: 28},
+		{Type: TokenEOF, Value: "", Line: 1, Column: 25, Offset: 29},
+	}
+
+	got := collectTokens(NewLexer(input))
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d tokens, got %d: %+v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Token[%d]: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestNonNullMarker(t *testing.T) {
+	log.Println("Starting TestNonNullMarker")
+	want := []Token{
+		{Type: TokenDollar, Value: "$"},
+		{Type: TokenIdent, Value: "id"},
+		{Type: TokenColon, Value: ":"},
+		{Type: TokenIdent, Value: "ID"},
+		{Type: TokenBang, Value: "!"},
+		{Type: TokenEOF, Value: ""},
+	}
+	assertTokens(t, collectTokens(NewLexer("$id: ID!")), want)
+}
+
+func TestDefaultValueEquals(t *testing.T) {
+	log.Println("Starting TestDefaultValueEquals")
+	want := []Token{
+		{Type: TokenDollar, Value: "$"},
+		{Type: TokenIdent, Value: "first"},
+		{Type: TokenColon, Value: ":"},
+		{Type: TokenIdent, Value: "Int"},
+		{Type: TokenEquals, Value: "="},
+		{Type: TokenInt, Value: "10"},
+		{Type: TokenEOF, Value: ""},
+	}
+	assertTokens(t, collectTokens(NewLexer("$first: Int = 10")), want)
+}
+
+func TestBrackets(t *testing.T) {
+	log.Println("Starting TestBrackets")
+	want := []Token{
+		{Type: TokenIdent, Value: "tags"},
+		{Type: TokenParenL, Value: "("},
+		{Type: TokenIdent, Value: "values"},
+		{Type: TokenColon, Value: ":"},
+		{Type: TokenBracketL, Value: "["},
+		{Type: TokenString, Value: "a"},
+		{Type: TokenString, Value: "b"},
+		{Type: TokenBracketR, Value: "]"},
+		{Type: TokenParenR, Value: ")"},
+		{Type: TokenEOF, Value: ""},
+	}
+	assertTokens(t, collectTokens(NewLexer(`tags(values: ["a", "b"])`)), want)
+}
+
+func TestVariableTokens(t *testing.T) {
+	log.Println("Starting TestVariableTokens")
+	want := []Token{
+		{Type: TokenIdent, Value: "user"},
+		{Type: TokenParenL, Value: "("},
+		{Type: TokenIdent, Value: "id"},
+		{Type: TokenColon, Value: ":"},
+		{Type: TokenDollar, Value: "$"},
+		{Type: TokenIdent, Value: "id"},
+		{Type: TokenParenR, Value: ")"},
+		{Type: TokenEOF, Value: ""},
+	}
+	assertTokens(t, collectTokens(NewLexer("user(id: $id)")), want)
+}
+
+func TestNumbers(t *testing.T) {
+	log.Println("Starting TestNumbers")
+	tests := []struct {
+		input string
+		want  Token
+	}{
+		{input: "10", want: Token{Type: TokenInt, Value: "10"}},
+		{input: "0", want: Token{Type: TokenInt, Value: "0"}},
+		{input: "-5", want: Token{Type: TokenInt, Value: "-5"}},
+		{input: "3.14", want: Token{Type: TokenFloat, Value: "3.14"}},
+		{input: "-0.5", want: Token{Type: TokenFloat, Value: "-0.5"}},
+		{input: "1e10", want: Token{Type: TokenFloat, Value: "1e10"}},
+		{input: "6.022e23", want: Token{Type: TokenFloat, Value: "6.022e23"}},
+		{input: "1.5E-3", want: Token{Type: TokenFloat, Value: "1.5E-3"}},
+		{input: "1.2.3", want: Token{Type: TokenIllegal, Value: "invalid number 1.2.3"}},
+		{input: "1.", want: Token{Type: TokenIllegal, Value: "invalid number 1."}},
+		{input: "1e", want: Token{Type: TokenIllegal, Value: "invalid number 1e"}},
+		{input: "007", want: Token{Type: TokenIllegal, Value: "invalid number 007"}},
+		{input: "12abc", want: Token{Type: TokenIllegal, Value: "invalid number 12abc"}},
+		{input: "-", want: Token{Type: TokenIllegal, Value: "invalid number -"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assertTokens(t, collectTokens(NewLexer(tt.input)), []Token{tt.want, {Type: TokenEOF}})
+		})
+	}
+}
+
+func TestNumberArguments(t *testing.T) {
+	log.Println("Starting TestNumberArguments")
+	want := []Token{
+		{Type: TokenIdent, Value: "users"},
+		{Type: TokenParenL, Value: "("},
+		{Type: TokenIdent, Value: "first"},
+		{Type: TokenColon, Value: ":"},
+		{Type: TokenInt, Value: "10"},
+		{Type: TokenIdent, Value: "lat"},
+		{Type: TokenColon, Value: ":"},
+		{Type: TokenFloat, Value: "37.7749"},
+		{Type: TokenParenR, Value: ")"},
+		{Type: TokenEOF, Value: ""},
+	}
+	assertTokens(t, collectTokens(NewLexer("users(first: 10, lat: 37.7749)")), want)
+}
+
+func TestCommasAreInsignificant(t *testing.T) {
+	log.Println("Starting TestCommasAreInsignificant")
+	want := []Token{
+		{Type: TokenIdent, Value: "a"},
+		{Type: TokenParenL, Value: "("},
+		{Type: TokenIdent, Value: "x"},
+		{Type: TokenColon, Value: ":"},
+		{Type: TokenString, Value: "1"},
+		{Type: TokenIdent, Value: "y"},
+		{Type: TokenColon, Value: ":"},
+		{Type: TokenString, Value: "2"},
+		{Type: TokenParenR, Value: ")"},
+		{Type: TokenEOF, Value: ""},
+	}
+	assertTokens(t, collectTokens(NewLexer(`a(x: "1", y: "2")`)), want)
+	assertTokens(t, collectTokens(NewLexer(`a(x: "1",,, y: "2",)`)), want)
+}
+
+func TestSpread(t *testing.T) {
+	log.Println("Starting TestSpread")
+	tests := []struct {
+		name  string
+		input string
+		want  []Token
+	}{
+		{
+			name:  "Fragment spread",
+			input: "...Frag",
+			want: []Token{
+				{Type: TokenSpread, Value: "..."},
+				{Type: TokenIdent, Value: "Frag"},
+				{Type: TokenEOF, Value: ""},
+			},
+		},
+		{
+			name:  "Inline fragment",
+			input: "... on User",
+			want: []Token{
+				{Type: TokenSpread, Value: "..."},
+				{Type: TokenIdent, Value: "on"},
+				{Type: TokenIdent, Value: "User"},
+				{Type: TokenEOF, Value: ""},
+			},
+		},
+		{
+			name:  "Two dots",
+			input: "..x",
+			want: []Token{
+				{Type: TokenIllegal, Value: "expected ... but found .."},
+				{Type: TokenIdent, Value: "x"},
+				{Type: TokenEOF, Value: ""},
+			},
+		},
+		{
+			name:  "Single dot",
+			input: ".x",
+			want: []Token{
+				{Type: TokenIllegal, Value: "expected ... but found ."},
+				{Type: TokenIdent, Value: "x"},
+				{Type: TokenEOF, Value: ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertTokens(t, collectTokens(NewLexer(tt.input)), tt.want)
+		})
+	}
+}
+
+func TestUnexpectedCharacters(t *testing.T) {
+	log.Println("Starting TestUnexpectedCharacters")
+	tests := []struct {
+		name  string
+		input string
+		want  []Token
+	}{
+		{
+			name:  "Pipe between fields",
+			input: `{ a | b }`,
+			want: []Token{
+				{Type: TokenBraceL, Value: "{"},
+				{Type: TokenIdent, Value: "a"},
+				{Type: TokenIllegal, Value: `unexpected character '|'`},
+				{Type: TokenIdent, Value: "b"},
+				{Type: TokenBraceR, Value: "}"},
+				{Type: TokenEOF, Value: ""},
+			},
+		},
+		{
+			name:  "Trailing garbage",
+			input: `} % x`,
+			want: []Token{
+				{Type: TokenBraceR, Value: "}"},
+				{Type: TokenIllegal, Value: `unexpected character '%'`},
+				{Type: TokenIdent, Value: "x"},
+				{Type: TokenEOF, Value: ""},
+			},
+		},
+		{
+			name:  "Adjacent characters",
+			input: `&?`,
+			want: []Token{
+				{Type: TokenIllegal, Value: `unexpected character '&'`},
+				{Type: TokenIllegal, Value: `unexpected character '?'`},
+				{Type: TokenEOF, Value: ""},
+			},
+		},
+		{
+			name:  "NUL is not the end of the input",
+			input: "{ a }\x00 garbage ((",
+			want: []Token{
+				{Type: TokenBraceL, Value: "{"},
+				{Type: TokenIdent, Value: "a"},
+				{Type: TokenBraceR, Value: "}"},
+				{Type: TokenIllegal, Value: `unexpected character '\x00'`},
+				{Type: TokenIdent, Value: "garbage"},
+				{Type: TokenParenL, Value: "("},
+				{Type: TokenParenL, Value: "("},
+				{Type: TokenEOF, Value: ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertTokens(t, collectTokens(NewLexer(tt.input)), tt.want)
+			assertTokens(t, collectTokens(NewLexerReader(strings.NewReader(tt.input))), tt.want)
+		})
+	}
+
+	tok := NewLexer("{\n  a & b }").Tokenize()[2]
+	if tok.Type != TokenIllegal || tok.Line != 2 || tok.Column != 5 {
+		t.Errorf("Expected an illegal token at line 2, column 5, got %+v", tok)
+	}
+}
+
+func TestStringEscapes(t *testing.T) {
+	log.Println("Starting TestStringEscapes")
+	tests := []struct {
+		name  string
+		input string
+		want  Token
+	}{
+		{name: "Quote", input: `"say \"hi\""`, want: Token{Type: TokenString, Value: `say "hi"`}},
+		{name: "Backslash", input: `"a\\b"`, want: Token{Type: TokenString, Value: `a\b`}},
+		{name: "Slash", input: `"a\/b"`, want: Token{Type: TokenString, Value: "a/b"}},
+		{name: "Backspace", input: `"a\bb"`, want: Token{Type: TokenString, Value: "a\bb"}},
+		{name: "Form feed", input: `"a\fb"`, want: Token{Type: TokenString, Value: "a\fb"}},
+		{name: "Newline", input: `"line\nbreak"`, want: Token{Type: TokenString, Value: "line\nbreak"}},
+		{name: "Carriage return", input: `"a\rb"`, want: Token{Type: TokenString, Value: "a\rb"}},
+		{name: "Tab", input: `"a\tb"`, want: Token{Type: TokenString, Value: "a\tb"}},
+		{name: "Unicode", input: `"caf\u00e9"`, want: Token{Type: TokenString, Value: "café"}},
+		{name: "Unicode uppercase hex", input: `"\u00E9"`, want: Token{Type: TokenString, Value: "é"}},
+		{name: "Surrogate pair", input: `"\uD83C\uDF89"`, want: Token{Type: TokenString, Value: "🎉"}},
+		{name: "Invalid escape", input: `"a\qb"`, want: Token{Type: TokenIllegal, Value: `invalid escape sequence \q`}},
+		{name: "Short unicode escape", input: `"\u00g"`, want: Token{Type: TokenIllegal, Value: "invalid unicode escape sequence"}},
+		{name: "Lone surrogate", input: `"\uD83C"`, want: Token{Type: TokenIllegal, Value: "invalid unicode escape sequence"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertTokens(t, collectTokens(NewLexer(tt.input)), []Token{tt.want, {Type: TokenEOF}})
+		})
+	}
+}
+
+func TestPeek(t *testing.T) {
+	log.Println("Starting TestPeek")
+	l := NewLexer("alias: name")
+
+	first := l.Peek()
+	if again := l.Peek(); again != first {
+		t.Errorf("Expected repeated Peek to return %+v, got %+v", first, again)
+	}
+	if next := l.NextToken(); next != first {
+		t.Errorf("Expected NextToken to return peeked %+v, got %+v", first, next)
+	}
+	if peeked := l.Peek(); peeked.Type != TokenColon {
+		t.Errorf("Expected to peek a colon, got %+v", peeked)
+	}
+
+	want := []Token{
+		{Type: TokenColon, Value: ":"},
+		{Type: TokenIdent, Value: "name"},
+		{Type: TokenEOF, Value: ""},
+	}
+	assertTokens(t, collectTokens(l), want)
+}
+
+func TestTokenize(t *testing.T) {
+	log.Println("Starting TestTokenize")
+	tests := []struct {
+		name  string
+		input string
+		want  []Token
+	}{
+		{
+			name:  "Small query",
+			input: `query X { a(b: 1) }`,
+			want: []Token{
+				{Type: TokenIdent, Value: "query"},
+				{Type: TokenIdent, Value: "X"},
+				{Type: TokenBraceL, Value: "{"},
+				{Type: TokenIdent, Value: "a"},
+				{Type: TokenParenL, Value: "("},
+				{Type: TokenIdent, Value: "b"},
+				{Type: TokenColon, Value: ":"},
+				{Type: TokenInt, Value: "1"},
+				{Type: TokenParenR, Value: ")"},
+				{Type: TokenBraceR, Value: "}"},
+				{Type: TokenEOF, Value: ""},
+			},
+		},
+		{
+			name:  "Empty input",
+			input: "",
+			want:  []Token{{Type: TokenEOF, Value: ""}},
+		},
+		{
+			name:  "Stops at illegal token",
+			input: `{ a(b: "open) }`,
+			want: []Token{
+				{Type: TokenBraceL, Value: "{"},
+				{Type: TokenIdent, Value: "a"},
+				{Type: TokenParenL, Value: "("},
+				{Type: TokenIdent, Value: "b"},
+				{Type: TokenColon, Value: ":"},
+				{Type: TokenIllegal, Value: "unterminated string"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertTokens(t, NewLexer(tt.input).Tokenize(), tt.want)
+		})
+	}
+}
+
+func TestNewLexerReader(t *testing.T) {
+	log.Println("Starting TestNewLexerReader")
+	input := "query X {\r\n  user(name: \"café \\ud83d\\ude00\", bio: \"\"\"a \\\"\"\" b\"\"\", first: 10) { id }\n}"
+	want := NewLexer(input).Tokenize()
+
+	tests := []struct {
+		name   string
+		reader io.Reader
+	}{
+		{name: "strings.Reader", reader: strings.NewReader(input)},
+		{name: "One byte at a time", reader: iotest.OneByteReader(strings.NewReader(input))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewLexerReader(tt.reader).Tokenize()
+			if len(got) != len(want) {
+				t.Fatalf("Expected %d tokens, got %d: %+v", len(want), len(got), got)
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Errorf("Token[%d]: expected %+v, got %+v", i, want[i], got[i])
+				}
+			}
+			if last := got[len(got)-1]; last.Type != TokenEOF {
+				t.Errorf("Expected final token to be EOF, got %+v", last)
+			}
+		})
+	}
+}
+
+func TestLexerReadsWithoutAllocating(t *testing.T) {
+	log.Println("Starting TestLexerReadsWithoutAllocating")
+	input := strings.Repeat("é { a } ", 1000)
+	lexers := map[string]*Lexer{
+		"String": NewLexer(input),
+		"Reader": NewLexerReader(strings.NewReader(input)),
+	}
+	for name, l := range lexers {
+		t.Run(name, func(t *testing.T) {
+			// Reading characters one at a time must reuse the lookahead buffer
+			allocs := testing.AllocsPerRun(10, func() {
+				for range 100 {
+					l.readChar()
+					l.peekChar(2)
+				}
+			})
+			if allocs != 0 {
+				t.Errorf("Expected no allocations, got %v", allocs)
+			}
+		})
+	}
+}
+
+func TestNextTokenWithoutAllocating(t *testing.T) {
+	log.Println("Starting TestNextTokenWithoutAllocating")
+	input := strings.Repeat("{ ( ) [ ] : ! = ... $ @ } ", 10)
+	l := NewLexer(input)
+	// Punctuation tokens carry constant values, so peeking and reading them allocates nothing
+	allocs := testing.AllocsPerRun(10, func() {
+		l.Reset(input)
+		for l.Peek().Type != TokenEOF {
+			l.NextToken()
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Expected no allocations, got %v", allocs)
+	}
+}
+
+func TestNewLexerReaderError(t *testing.T) {
+	log.Println("Starting TestNewLexerReaderError")
+	reader := io.MultiReader(strings.NewReader("query X"), iotest.ErrReader(errors.New("connection reset")))
+	want := []Token{
+		{Type: TokenIdent, Value: "query"},
+		{Type: TokenIdent, Value: "X"},
+		{Type: TokenIllegal, Value: "could not read input: connection reset"},
+	}
+	assertTokens(t, NewLexerReader(reader).Tokenize(), want)
+}
+
+func TestPosition(t *testing.T) {
+	log.Println("Starting TestPosition")
+	l := NewLexer("query X { a }")
+	want := []struct {
+		value  string
+		offset int
+	}{
+		{value: "query", offset: 0},
+		{value: "X", offset: 6},
+		{value: "{", offset: 8},
+		{value: "a", offset: 10},
+		{value: "}", offset: 12},
+		{value: "", offset: 13},
+	}
+
+	for _, w := range want {
+		tok := l.NextToken()
+		if tok.Value != w.value || tok.Offset != w.offset {
+			t.Errorf("Expected %q at offset %d, got %q at offset %d", w.value, w.offset, tok.Value, tok.Offset)
+		}
+		// Peeking ahead must not move the reported position
+		l.Peek()
+		if got := l.Position(); got != w.offset {
+			t.Errorf("Expected Position() %d after %q, got %d", w.offset, w.value, got)
+		}
+	}
+}
+
+func TestLexerReset(t *testing.T) {
+	log.Println("Starting TestLexerReset")
+	l := NewLexerWithComments("query # note\n{ a")
+	l.NextToken()
+	l.Peek()
+
+	l.Reset("# again\n{ b }")
+	want := []Token{
+		{Type: TokenComment, Value: " again", Line: 1, Column: 1, Offset: 0},
+		{Type: TokenBraceL, Value: "{", Line: 2, Column: 1, Offset: 8},
+		{Type: TokenIdent, Value: "b", Line: 2, Column: 3, Offset: 10},
+		{Type: TokenBraceR, Value: "}", Line: 2, Column: 5, Offset: 12},
+		{Type: TokenEOF, Value: "", Line: 2, Column: 6, Offset: 13},
+	}
+	got := collectTokens(l)
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d tokens, got %d: %+v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Token[%d]: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func BenchmarkLexer(b *testing.B) {
+	var large strings.Builder
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&large, `field%d: item(id: $id, index: %d, note: "text é", ratio: 1.5e3) @include(if: true) { id } `, i, i)
+	}
+	fixtures := []struct{ name, input string }{
+		{name: "Small", input: `{ viewer { id name } }`},
+		{name: "Medium", input: `query GetUser($id: ID!) { user(id: $id) { id name friends(first: 10) { name avatar(size: 32) } } } # trailing comment`},
+		{name: "DeeplyNested", input: strings.Repeat("{ a ", 100) + strings.Repeat("}", 100)},
+		{name: "LargeGenerated", input: "query Large {" + large.String() + "}"},
+	}
+
+	for _, fixture := range fixtures {
+		b.Run(fixture.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(fixture.input)))
+			for i := 0; i < b.N; i++ {
+				l := NewLexer(fixture.input)
+				for tok := l.NextToken(); tok.Type != TokenEOF; tok = l.NextToken() {
+					if tok.Type == TokenIllegal {
+						b.Fatalf("Unexpected illegal token: %s", tok.Value)
+					}
+				}
+			}
+		})
+	}
+}
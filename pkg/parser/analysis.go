@@ -0,0 +1,455 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FieldsAtDepth returns all field nodes found at exactly the given nesting depth.
+// Root fields of an operation are at depth 1, their children at depth 2, and so on.
+// Inline fragments do not add a level; their fields count at the depth of the
+// selection set that contains them. As with Depth, when n is a document the fields
+// of all its operations are returned and fragment spreads are resolved against
+// its fragment definitions, so a fragment's fields count at the depth of the
+// spread. Within an operation, a fragment spread more than once at the same depth
+// contributes its fields once. Spreads that cannot be resolved or that recurse
+// into themselves are skipped.
+func FieldsAtDepth(n *Node, depth int) []*Node {
+	if n == nil || depth < 1 {
+		return nil
+	}
+	var fields []*Node
+	if n.Type != NodeDocument {
+		collectFieldsAtDepth(n.SelectionSet, 1, depth, nil, nil, nil, &fields)
+		return fields
+	}
+	fragments := fragmentDefinitions(n)
+	for _, operation := range n.Operations() {
+		collectFieldsAtDepth(operation.SelectionSet, 1, depth, fragments, make(map[string]bool), make(map[fragmentAtDepth]bool), &fields)
+	}
+	return fields
+}
+
+// fragmentAtDepth identifies the expansion of a fragment at a nesting depth
+type fragmentAtDepth struct {
+	name  string
+	depth int
+}
+
+// collectFieldsAtDepth walks a selection set, appending fields that sit at the
+// target depth and expanding fragment spreads found in fragments. It uses
+// visiting to stop at cyclic spreads and expanded to expand each fragment at
+// most once per depth, so repeated spreads cannot multiply the work.
+func collectFieldsAtDepth(selections []*Node, current, target int, fragments map[string]*Node, visiting map[string]bool, expanded map[fragmentAtDepth]bool, fields *[]*Node) {
+	for _, child := range selections {
+		switch child.Type {
+		case NodeInlineFragment:
+			collectFieldsAtDepth(child.SelectionSet, current, target, fragments, visiting, expanded, fields)
+		case NodeFragmentSpread:
+			fragment, ok := fragments[child.Name]
+			key := fragmentAtDepth{child.Name, current}
+			if !ok || visiting[child.Name] || expanded[key] {
+				continue
+			}
+			visiting[child.Name] = true
+			expanded[key] = true
+			collectFieldsAtDepth(fragment.SelectionSet, current, target, fragments, visiting, expanded, fields)
+			delete(visiting, child.Name)
+		case NodeField:
+			if current == target {
+				*fields = append(*fields, child)
+				continue
+			}
+			collectFieldsAtDepth(child.SelectionSet, current+1, target, fragments, visiting, expanded, fields)
+		}
+	}
+}
+
+// fragmentDefinitions returns the fragment definitions of a document keyed by name
+func fragmentDefinitions(document *Node) map[string]*Node {
+	fragments := make(map[string]*Node)
+	for _, definition := range document.SelectionSet {
+		if definition.Type == NodeFragmentDefinition {
+			fragments[definition.Name] = definition
+		}
+	}
+	return fragments
+}
+
+// Depth returns the maximum nesting depth of the node's selection set, where a
+// query selecting only scalar fields has depth 1. Inline fragments do not add a
+// level. When n is a document, fragment spreads are resolved against the
+// document's fragment definitions and the deepest operation is reported; a spread
+// that cannot be resolved counts as a single level. Each fragment's depth is
+// computed once, so the cost is linear in the size of the document however often
+// its fragments are spread.
+func (n *Node) Depth() int {
+	if n == nil {
+		return 0
+	}
+	if n.Type != NodeDocument {
+		return selectionDepth(n.SelectionSet, nil, nil, nil)
+	}
+
+	fragments := fragmentDefinitions(n)
+	depths := make(map[string]int)
+	depth := 0
+	for _, operation := range n.Operations() {
+		depth = max(depth, selectionDepth(operation.SelectionSet, fragments, make(map[string]bool), depths))
+	}
+	return depth
+}
+
+// selectionDepth returns the depth of a selection set, expanding fragment spreads
+// found in fragments, using visiting to stop at cyclic spreads and recording the
+// depth of each expanded fragment in depths for later spreads of it
+func selectionDepth(selections []*Node, fragments map[string]*Node, visiting map[string]bool, depths map[string]int) int {
+	depth := 0
+	for _, child := range selections {
+		var d int
+		switch child.Type {
+		case NodeField:
+			d = 1 + selectionDepth(child.SelectionSet, fragments, visiting, depths)
+		case NodeInlineFragment:
+			d = selectionDepth(child.SelectionSet, fragments, visiting, depths)
+		case NodeFragmentSpread:
+			if known, ok := depths[child.Name]; ok {
+				d = known
+				break
+			}
+			fragment, ok := fragments[child.Name]
+			if !ok || visiting[child.Name] {
+				d = 1
+				break
+			}
+			visiting[child.Name] = true
+			d = selectionDepth(fragment.SelectionSet, fragments, visiting, depths)
+			delete(visiting, child.Name)
+			depths[child.Name] = d
+		}
+		depth = max(depth, d)
+	}
+	return depth
+}
+
+// maxComplexity caps the value returned by Complexity so that nested page sizes cannot overflow
+const maxComplexity = math.MaxInt32
+
+// Complexity estimates the cost of resolving the node's selection set. Each field
+// costs one, and the selections of a field with a positive integer first or last
+// argument, as used for paginated lists, are counted that many times. Inline
+// fragments cost nothing themselves. When n is a document, fragment spreads are
+// resolved against its fragment definitions and the costs of all its operations
+// are added up; a spread that cannot be resolved or that recurses into itself
+// costs nothing. The result is capped at math.MaxInt32. As with Depth, each
+// fragment's complexity is computed once.
+func (n *Node) Complexity() int {
+	if n == nil {
+		return 0
+	}
+	if n.Type != NodeDocument {
+		return selectionComplexity(n.SelectionSet, nil, nil, nil)
+	}
+	fragments := fragmentDefinitions(n)
+	costs := make(map[string]int)
+	complexity := 0
+	for _, operation := range n.Operations() {
+		complexity = min(complexity+selectionComplexity(operation.SelectionSet, fragments, make(map[string]bool), costs), maxComplexity)
+	}
+	return complexity
+}
+
+// selectionComplexity returns the complexity of a selection set, expanding
+// fragment spreads found in fragments, using visiting to stop at cyclic spreads
+// and recording the complexity of each expanded fragment in costs for later
+// spreads of it
+func selectionComplexity(selections []*Node, fragments map[string]*Node, visiting map[string]bool, costs map[string]int) int {
+	complexity := 0
+	for _, child := range selections {
+		var c int
+		switch child.Type {
+		case NodeField:
+			c = 1 + pageSize(child)*selectionComplexity(child.SelectionSet, fragments, visiting, costs)
+		case NodeInlineFragment:
+			c = selectionComplexity(child.SelectionSet, fragments, visiting, costs)
+		case NodeFragmentSpread:
+			if known, ok := costs[child.Name]; ok {
+				c = known
+				break
+			}
+			fragment, ok := fragments[child.Name]
+			if !ok || visiting[child.Name] {
+				break
+			}
+			visiting[child.Name] = true
+			c = selectionComplexity(fragment.SelectionSet, fragments, visiting, costs)
+			delete(visiting, child.Name)
+			costs[child.Name] = c
+		}
+		complexity = min(complexity+c, maxComplexity)
+	}
+	return complexity
+}
+
+// pageSize returns the number of items a field requests with a positive integer
+// first or last argument, or 1 if it has neither
+func pageSize(field *Node) int {
+	for _, name := range []string{"first", "last"} {
+		value, ok := field.Argument(name).(*IntValue)
+		if !ok {
+			continue
+		}
+		if size, err := strconv.Atoi(value.Raw); err == nil && size > 0 {
+			return min(size, maxComplexity)
+		}
+	}
+	return 1
+}
+
+// LeafPaths returns the dotted path of every leaf field, such as user.friends.name,
+// built from aliases where present. Inline fragments do not add a path segment.
+// Named fragment spreads are skipped; use ExpandedLeafPaths to follow them. When n
+// is a document, the paths of all its operations are returned. Each path appears
+// once, in source order.
+func (n *Node) LeafPaths() []string {
+	return n.leafPaths(false)
+}
+
+// ExpandedLeafPaths is like LeafPaths, but follows fragment spreads into the
+// fragment definitions of n when it is a document. Spreads that cannot be
+// resolved, and spreads that would recurse into themselves, are skipped. A
+// fragment spread more than once under the same path is expanded once, and
+// after maxFragmentExpansions expansions further spreads are skipped, so that
+// fragments nested to multiply the number of paths cannot exhaust memory.
+func (n *Node) ExpandedLeafPaths() []string {
+	return n.leafPaths(true)
+}
+
+// leafPaths collects leaf field paths, expanding fragment spreads if requested
+func (n *Node) leafPaths(expandFragments bool) []string {
+	if n == nil {
+		return nil
+	}
+	collector := &leafPathCollector{seen: make(map[string]bool)}
+	if n.Type != NodeDocument {
+		collector.collect(n.SelectionSet, "")
+		return collector.paths
+	}
+
+	if expandFragments {
+		collector.fragments = fragmentDefinitions(n)
+		collector.visiting = make(map[string]bool)
+		collector.expanded = make(map[fragmentAtPath]bool)
+	}
+	for _, operation := range n.Operations() {
+		collector.collect(operation.SelectionSet, "")
+	}
+	return collector.paths
+}
+
+// maxFragmentExpansions bounds the fragment spreads ExpandedLeafPaths follows
+const maxFragmentExpansions = 10000
+
+// fragmentAtPath identifies the expansion of a fragment below a leaf path prefix
+type fragmentAtPath struct {
+	name   string
+	prefix string
+}
+
+// leafPathCollector accumulates unique leaf paths while walking selection sets
+type leafPathCollector struct {
+	fragments map[string]*Node        // Nil when fragment spreads are skipped
+	visiting  map[string]bool         // Fragments on the current path, to stop at cycles
+	expanded  map[fragmentAtPath]bool // Fragments already expanded, whose paths are known
+	seen      map[string]bool
+	paths     []string
+}
+
+// collect records the leaf paths below a selection set whose parent path is prefix
+func (c *leafPathCollector) collect(selections []*Node, prefix string) {
+	for _, child := range selections {
+		switch child.Type {
+		case NodeField:
+			path := child.ResponseKey()
+			if prefix != "" {
+				path = prefix + "." + path
+			}
+			if len(child.SelectionSet) > 0 {
+				c.collect(child.SelectionSet, path)
+			} else if !c.seen[path] {
+				c.seen[path] = true
+				c.paths = append(c.paths, path)
+			}
+		case NodeInlineFragment:
+			c.collect(child.SelectionSet, prefix)
+		case NodeFragmentSpread:
+			fragment, ok := c.fragments[child.Name]
+			key := fragmentAtPath{child.Name, prefix}
+			if !ok || c.visiting[child.Name] || c.expanded[key] || len(c.expanded) >= maxFragmentExpansions {
+				continue
+			}
+			c.visiting[child.Name] = true
+			c.expanded[key] = true
+			c.collect(fragment.SelectionSet, prefix)
+			delete(c.visiting, child.Name)
+		}
+	}
+}
+
+// FieldVariableDependencies maps the dotted path of each field, built from aliases
+// where present, to the names of the
+// variables referenced by that field's own arguments. Fields whose arguments
+// reference no variables are omitted.
+func FieldVariableDependencies(n *Node) map[string][]string {
+	deps := make(map[string][]string)
+	if n != nil {
+		collectFieldVariables(n.SelectionSet, "", deps)
+	}
+	return deps
+}
+
+// collectFieldVariables records the variables used by each field in a selection set and its descendants
+func collectFieldVariables(selections []*Node, prefix string, deps map[string][]string) {
+	for _, child := range selections {
+		if child.Type == NodeInlineFragment {
+			collectFieldVariables(child.SelectionSet, prefix, deps)
+			continue
+		}
+		if child.Type != NodeField {
+			continue
+		}
+		path := child.ResponseKey()
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+
+		var names []string
+		for _, arg := range child.Arguments {
+			names = appendVariables(names, arg.Value)
+		}
+		if len(names) > 0 {
+			sort.Strings(names)
+			deps[path] = names
+		}
+		collectFieldVariables(child.SelectionSet, path, deps)
+	}
+}
+
+// appendVariables appends the names of all variables referenced within a value, skipping duplicates
+func appendVariables(names []string, v Value) []string {
+	switch v := v.(type) {
+	case *VariableValue:
+		if !slices.Contains(names, v.Name) {
+			names = append(names, v.Name)
+		}
+	case *ListValue:
+		for _, item := range v.Values {
+			names = appendVariables(names, item)
+		}
+	case *ObjectValue:
+		for _, field := range v.Fields {
+			names = appendVariables(names, field.Value)
+		}
+	}
+	return names
+}
+
+// FindFields returns every field named name anywhere in the tree rooted at n,
+// matching the field name rather than its alias, in source order. Fields inside
+// fragment definitions are included when n is a document, but fragment spreads
+// are not followed.
+func (n *Node) FindFields(name string) []*Node {
+	var fields []*Node
+	Walk(n, func(node *Node) bool {
+		if node.Type == NodeField && node.Name == name {
+			fields = append(fields, node)
+		}
+		return true
+	})
+	return fields
+}
+
+// FindDirectives returns every directive with the given name applied anywhere in
+// the tree rooted at n, including operations, fields, fragments, and fragment spreads
+func FindDirectives(n *Node, name string) []*Node {
+	var directives []*Node
+	Walk(n, func(node *Node) bool {
+		if node.Type == NodeDirective && node.Name == name {
+			directives = append(directives, node)
+		}
+		return true
+	})
+	return directives
+}
+
+// FindDuplicateFields returns the dotted paths of fields selected more than once
+// within the same selection set under the same response key and with identical
+// arguments, such as the second name in { user { name name } }. Argument order
+// does not matter. Each duplicated field is reported once, in source order. Inline
+// fragments and fragment definitions are checked as separate selection sets.
+func FindDuplicateFields(n *Node) []string {
+	var duplicates []string
+	if n != nil {
+		collectDuplicateFields(n.SelectionSet, "", &duplicates)
+	}
+	return duplicates
+}
+
+// collectDuplicateFields reports repeated fields in a selection set and its descendants
+func collectDuplicateFields(selections []*Node, prefix string, duplicates *[]string) {
+	seen := make(map[string]int)
+	for _, child := range selections {
+		if child.Type != NodeField {
+			collectDuplicateFields(child.SelectionSet, prefix, duplicates)
+			continue
+		}
+		path := child.ResponseKey()
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+
+		key := child.Name + "(" + argumentsKey(child.Arguments) + ")"
+		if child.Alias != "" {
+			key = child.Alias + ": " + key
+		}
+		seen[key]++
+		if seen[key] == 2 {
+			*duplicates = append(*duplicates, path)
+		}
+		collectDuplicateFields(child.SelectionSet, path, duplicates)
+	}
+}
+
+// argumentsKey renders arguments sorted by name so that equal argument lists
+// produce the same key regardless of order
+func argumentsKey(args []Argument) string {
+	rendered := make([]string, len(args))
+	for i, arg := range args {
+		rendered[i] = arg.Name + ": " + arg.Value.String()
+	}
+	sort.Strings(rendered)
+	return strings.Join(rendered, ", ")
+}
+
+// DirectiveNames counts how often each directive is applied anywhere in the tree
+// rooted at n, including operations, variable definitions, fields, fragment
+// definitions, fragment spreads, and inline fragments
+func (n *Node) DirectiveNames() map[string]int {
+	counts := make(map[string]int)
+	Walk(n, func(node *Node) bool {
+		if node.Type == NodeDirective {
+			counts[node.Name]++
+		}
+		for _, definition := range node.VariableDefinitions {
+			for _, directive := range definition.Directives {
+				counts[directive.Name]++
+			}
+		}
+		return true
+	})
+	return counts
+}
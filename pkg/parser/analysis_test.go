@@ -0,0 +1,464 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFieldsAtDepth(t *testing.T) {
+	log.Println("Starting TestFieldsAtDepth")
+	input := `query GetUser { user(id: "123") { name friends { name } } }`
+	tests := []struct {
+		name  string
+		depth int
+		want  []string
+	}{
+		{name: "Root fields", depth: 1, want: []string{"user"}},
+		{name: "Second level", depth: 2, want: []string{"name", "friends"}},
+		{name: "Leaves", depth: 3, want: []string{"name"}},
+		{name: "Beyond deepest level", depth: 4, want: nil},
+		{name: "Invalid depth", depth: 0, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := mustParseQuery(t, input)
+			got := FieldsAtDepth(query, tt.depth)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %d fields at depth %d, got %d", len(tt.want), tt.depth, len(got))
+			}
+			for i, field := range got {
+				if field.Name != tt.want[i] {
+					t.Errorf("Field[%d]: expected %s, got %s", i, tt.want[i], field.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestFieldsAtDepthFragments(t *testing.T) {
+	log.Println("Starting TestFieldsAtDepthFragments")
+	input := `
+		query Q { ...F viewer { ... on User { ...Name } } }
+		query R { user { ...Missing id } }
+		fragment F on Query { user { name friends { ...Name } } }
+		fragment Name on User { name ...Name }
+	`
+	document, err := NewParser(input).ParseDocument()
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+	tests := []struct {
+		name  string
+		depth int
+		want  []string
+	}{
+		{name: "Root fields", depth: 1, want: []string{"user", "viewer", "user"}},
+		{name: "Second level", depth: 2, want: []string{"name", "friends", "name", "id"}},
+		{name: "Third level", depth: 3, want: []string{"name"}},
+		{name: "Beyond deepest level", depth: 4, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, field := range FieldsAtDepth(document, tt.depth) {
+				got = append(got, field.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	spread, err := NewParser(`query Q { ...F } fragment F on Query { user { name } }`).ParseDocument()
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+	if got := FieldsAtDepth(spread, 1); len(got) != 1 || got[0].Name != "user" {
+		t.Errorf("Expected the spread's user field at depth 1, got %v", got)
+	}
+
+	// The deepest level with fields matches the depth of the document
+	if depth := document.Depth(); len(FieldsAtDepth(document, depth)) == 0 || len(FieldsAtDepth(document, depth+1)) != 0 {
+		t.Errorf("Expected the deepest fields at depth %d", depth)
+	}
+}
+
+func TestLeafPaths(t *testing.T) {
+	log.Println("Starting TestLeafPaths")
+	query := mustParseQuery(t, `query GetUser { user(id: 1) { id pals: friends { name ... on Admin { role } } } viewer { id } }`)
+	want := []string{"user.id", "user.pals.name", "user.pals.role", "viewer.id"}
+	if got := query.LeafPaths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestLeafPathsFragments(t *testing.T) {
+	log.Println("Starting TestLeafPathsFragments")
+	input := `
+		query Feed { posts { id ...PostFields author { ...Self } } }
+		query Again { posts { id } }
+		fragment PostFields on Post { title comments { ...Missing body } }
+		fragment Self on User { name friends { ...Self } }
+	`
+	document, err := NewParser(input).ParseDocument()
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+
+	tests := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{
+			name: "Spreads skipped",
+			got:  document.LeafPaths(),
+			want: []string{"posts.id"},
+		},
+		{
+			name: "Spreads expanded",
+			got:  document.ExpandedLeafPaths(),
+			want: []string{"posts.id", "posts.title", "posts.comments.body", "posts.author.name"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, tt.got)
+			}
+		})
+	}
+}
+
+func TestFieldVariableDependencies(t *testing.T) {
+	log.Println("Starting TestFieldVariableDependencies")
+	input := `query GetUser { user(id: $id) { name posts(first: $n) { title } friends(filter: { team: $team, owner: $id }) { name } } }`
+	want := map[string][]string{
+		"user":         {"id"},
+		"user.posts":   {"n"},
+		"user.friends": {"id", "team"},
+	}
+
+	got := FieldVariableDependencies(mustParseQuery(t, input))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestFieldsAtDepthInlineFragments(t *testing.T) {
+	log.Println("Starting TestFieldsAtDepthInlineFragments")
+	query := mustParseQuery(t, `{ node(id: $id) { id ... on User { name ... on Admin { permissions(scope: $scope) } } } }`)
+
+	got := FieldsAtDepth(query, 2)
+	want := []string{"id", "name", "permissions"}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d fields at depth 2, got %d", len(want), len(got))
+	}
+	for i, field := range got {
+		if field.Name != want[i] {
+			t.Errorf("Field[%d]: expected %s, got %s", i, want[i], field.Name)
+		}
+	}
+
+	deps := FieldVariableDependencies(query)
+	wantDeps := map[string][]string{"node": {"id"}, "node.permissions": {"scope"}}
+	if !reflect.DeepEqual(deps, wantDeps) {
+		t.Errorf("Expected %v, got %v", wantDeps, deps)
+	}
+}
+
+func TestDepth(t *testing.T) {
+	log.Println("Starting TestDepth")
+	tests := []struct {
+		name  string
+		input string
+		want  int
+	}{
+		{name: "Flat query", input: `{ id name }`, want: 1},
+		{name: "Three levels", input: `query Q { user { friends { name } } }`, want: 3},
+		{name: "Deepest branch wins", input: `{ a { b } c { d { e { f } } } }`, want: 4},
+		{name: "Inline fragments add no level", input: `{ node { ... on User { friends { name } } } }`, want: 3},
+		{
+			name: "Fragment spreads are expanded",
+			input: `
+				query Q { user { ...Friends } }
+				fragment Friends on User { friends { ...Name } }
+				fragment Name on User { profile { name } }
+			`,
+			want: 4,
+		},
+		{name: "Unknown fragment counts as one level", input: `{ user { ...Missing } }`, want: 2},
+		{
+			name: "Cyclic fragments terminate",
+			input: `
+				{ user { ...A } }
+				fragment A on User { friends { ...A } }
+			`,
+			want: 3,
+		},
+		{
+			name: "Deepest operation in a document",
+			input: `
+				query Shallow { id }
+				query Deep { a { b } }
+			`,
+			want: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			document, err := NewParser(tt.input).ParseDocument()
+			if err != nil {
+				t.Fatalf("Unexpected parse error: %s", err)
+			}
+			if got := document.Depth(); got != tt.want {
+				t.Errorf("Expected depth %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestComplexity(t *testing.T) {
+	log.Println("Starting TestComplexity")
+	tests := []struct {
+		name  string
+		input string
+		want  int
+	}{
+		{name: "Flat query", input: `{ id name }`, want: 2},
+		{name: "Nested fields", input: `{ user { id friends { name } } }`, want: 4},
+		{name: "Page size multiplies selections", input: `{ posts(first: 10) { id title } }`, want: 21},
+		{name: "Last argument", input: `{ posts(last: 5) { id } }`, want: 6},
+		{name: "Nested pages", input: `{ users(first: 10) { posts(first: 5) { id } } }`, want: 1 + 10*(1+5)},
+		{name: "Non-integer page sizes are ignored", input: `{ a(first: $n) { id } b(first: 0) { id } }`, want: 4},
+		{name: "Inline fragments cost nothing", input: `{ node { ... on User { id } } }`, want: 2},
+		{
+			name: "Fragment spreads are expanded",
+			input: `
+				query Q { posts(first: 3) { ...Post } }
+				fragment Post on Post { id author { name } }
+			`,
+			want: 1 + 3*3,
+		},
+		{name: "Unknown fragments cost nothing", input: `{ user { ...Missing } }`, want: 1},
+		{
+			name: "Cyclic fragments terminate",
+			input: `
+				{ user { ...A } }
+				fragment A on User { friends { ...A } }
+			`,
+			want: 2,
+		},
+		{name: "Operations are added up", input: `query A { a b } query B { c }`, want: 3},
+		{name: "Capped", input: `{ a(first: 2000000000) { b(first: 2000000000) { c(first: 2000000000) { d } } } }`, want: math.MaxInt32},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			document, err := NewParser(tt.input).ParseDocument()
+			if err != nil {
+				t.Fatalf("Unexpected parse error: %s", err)
+			}
+			if got := document.Complexity(); got != tt.want {
+				t.Errorf("Expected complexity %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+// fragmentChain returns a document whose n fragments each spread the next one
+// twice, using body to build a fragment's selections from the next fragment's name
+func fragmentChain(t *testing.T, n int, body func(next string) string) *Node {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString("query Q { root { ...F0 } }\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "fragment F%d on T { %s }\n", i, body(fmt.Sprintf("F%d", i+1)))
+	}
+	fmt.Fprintf(&b, "fragment F%d on T { leaf }\n", n)
+	document, err := NewParser(b.String()).ParseDocument()
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+	return document
+}
+
+func TestFragmentExpansionIsNotExponential(t *testing.T) {
+	log.Println("Starting TestFragmentExpansionIsNotExponential")
+	// Expanding every spread would visit 2^60 fragments and never finish
+	const n = 60
+
+	flat := fragmentChain(t, n, func(next string) string { return "..." + next + " ..." + next })
+	if got := flat.Depth(); got != 2 {
+		t.Errorf("Expected depth 2, got %d", got)
+	}
+	if got := flat.Complexity(); got != math.MaxInt32 {
+		t.Errorf("Expected complexity %d, got %d", math.MaxInt32, got)
+	}
+	if got := FieldsAtDepth(flat, 2); len(got) != 1 || got[0].Name != "leaf" {
+		t.Errorf("Expected the leaf field once, got %v", got)
+	}
+	if got, want := flat.ExpandedLeafPaths(), []string{"root.leaf"}; !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	nested := fragmentChain(t, n, func(next string) string { return "a { ..." + next + " } b { ..." + next + " }" })
+	if got := nested.Depth(); got != n+2 {
+		t.Errorf("Expected depth %d, got %d", n+2, got)
+	}
+	if got := nested.Complexity(); got != math.MaxInt32 {
+		t.Errorf("Expected complexity %d, got %d", math.MaxInt32, got)
+	}
+	if got := FieldsAtDepth(nested, n+2); len(got) != 1 || got[0].Name != "leaf" {
+		t.Errorf("Expected the leaf field once, got %v", got)
+	}
+	if got := nested.ExpandedLeafPaths(); len(got) == 0 || len(got) > maxFragmentExpansions {
+		t.Errorf("Expected at most %d paths, got %d", maxFragmentExpansions, len(got))
+	}
+}
+
+func TestFindFields(t *testing.T) {
+	log.Println("Starting TestFindFields")
+	input := `
+		query Feed { name viewer { handle: name friends { name } } posts { ... on Video { author { name } } } }
+		fragment Owner on Post { owner { name } }
+	`
+	document, err := NewParser(input).ParseDocument()
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+
+	tests := []struct {
+		name      string
+		field     string
+		wantCount int
+	}{
+		{name: "Several depths", field: "name", wantCount: 5},
+		{name: "Single match", field: "friends", wantCount: 1},
+		{name: "Alias is not a name", field: "handle", wantCount: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found := document.FindFields(tt.field)
+			if len(found) != tt.wantCount {
+				t.Fatalf("Expected %d %s fields, got %d", tt.wantCount, tt.field, len(found))
+			}
+			for i, field := range found {
+				if field.Type != NodeField || field.Name != tt.field {
+					t.Errorf("Match[%d]: expected field %s, got %s %s", i, tt.field, field.Type, field.Name)
+				}
+			}
+		})
+	}
+
+	found := document.FindFields("name")
+	if found[1].Alias != "handle" {
+		t.Errorf("Expected the second match to be the aliased viewer field, got %s", found[1].ResponseKey())
+	}
+	query := document.SelectionSet[0]
+	for i, depth := range []int{1, 2, 3, 3} {
+		if !slices.Contains(FieldsAtDepth(query, depth), found[i]) {
+			t.Errorf("Match[%d]: expected it at depth %d", i, depth)
+		}
+	}
+}
+
+func TestFindDirectives(t *testing.T) {
+	log.Println("Starting TestFindDirectives")
+	input := `
+		query Feed @cache(ttl: 1) {
+			viewer @cache(ttl: 2) { name @deprecated }
+			posts { ...PostFields @cache(ttl: 3) ... on Video @cache(ttl: 4) { duration } }
+		}
+		fragment PostFields on Post @cache(ttl: 5) { title { text @cache(ttl: 6) } }
+	`
+	document, err := NewParser(input).ParseDocument()
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+
+	found := FindDirectives(document, "cache")
+	want := []DirectiveLocation{
+		LocationQuery,
+		LocationField,
+		LocationFragmentSpread,
+		LocationInlineFragment,
+		LocationFragmentDefinition,
+		LocationField,
+	}
+	if len(found) != len(want) {
+		t.Fatalf("Expected %d @cache directives, got %d", len(want), len(found))
+	}
+	for i, directive := range found {
+		if directive.Location != want[i] {
+			t.Errorf("Directive[%d]: expected location %s, got %s", i, want[i], directive.Location)
+		}
+		if ttl, ok := directive.Argument("ttl").(*IntValue); !ok || ttl.Raw != strconv.Itoa(i+1) {
+			t.Errorf("Directive[%d]: expected ttl %d, got %v", i, i+1, ttl)
+		}
+	}
+
+	if got := FindDirectives(document, "skip"); len(got) != 0 {
+		t.Errorf("Expected no @skip directives, got %d", len(got))
+	}
+}
+
+func TestFindDuplicateFields(t *testing.T) {
+	log.Println("Starting TestFindDuplicateFields")
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{name: "Repeated field", input: `{ user { name name } }`, want: []string{"user.name"}},
+		{name: "Reported once", input: `{ user { name id name name } }`, want: []string{"user.name"}},
+		{name: "Identical arguments in any order", input: `{ a: avatar(size: 32, round: true) a: avatar(round: true, size: 32) }`, want: []string{"a"}},
+		{name: "Different arguments", input: `{ user(id: "1") { name } user(id: "2") { name } }`, want: nil},
+		{name: "String and int arguments", input: `{ user(id: "1") { name } user(id: 1) { name } }`, want: nil},
+		{name: "Different aliases", input: `{ user { first: name second: name } }`, want: nil},
+		{name: "Nested in repeated parents", input: `{ user { name } user { name name } }`, want: []string{"user", "user.name"}},
+		{name: "Inline fragment is a separate set", input: `{ node { id ... on User { id name name } } }`, want: []string{"node.name"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FindDuplicateFields(mustParseQuery(t, tt.input))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDirectiveNames(t *testing.T) {
+	log.Println("Starting TestDirectiveNames")
+	input := `
+		query Feed($id: ID @persist) @persist {
+			viewer @cache(ttl: 60) { name }
+			posts { ...PostFields @trace ... on Video @cache(ttl: 5) { duration } }
+		}
+		fragment PostFields on Post @trace(label: "posts") { title }
+	`
+	document, err := NewParser(input).ParseDocument()
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+
+	want := map[string]int{"persist": 2, "trace": 2, "cache": 2}
+	if got := document.DirectiveNames(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if got := mustParseQuery(t, `{ viewer { name } }`).DirectiveNames(); len(got) != 0 {
+		t.Errorf("Expected no directives, got %v", got)
+	}
+}
@@ -0,0 +1,150 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+import "encoding/json"
+
+// astKinds maps node types to the kind names used by graphql-js
+var astKinds = map[NodeType]string{
+	NodeDocument:           "Document",
+	NodeQuery:              "OperationDefinition",
+	NodeMutation:           "OperationDefinition",
+	NodeSubscription:       "OperationDefinition",
+	NodeField:              "Field",
+	NodeDirective:          "Directive",
+	NodeFragmentDefinition: "FragmentDefinition",
+	NodeFragmentSpread:     "FragmentSpread",
+	NodeInlineFragment:     "InlineFragment",
+}
+
+// MarshalJSON encodes the node as a graphql-js style AST
+func (n *Node) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.ToAST())
+}
+
+// ToAST converts the node into a graphql-js style AST made of maps and slices,
+// with kind, name, arguments, directives, and selectionSet keys as appropriate
+func (n *Node) ToAST() map[string]any {
+	ast := map[string]any{"kind": astKinds[n.Type]}
+	switch n.Type {
+	case NodeDocument:
+		definitions := make([]any, len(n.SelectionSet))
+		for i, definition := range n.SelectionSet {
+			definitions[i] = definition.ToAST()
+		}
+		ast["definitions"] = definitions
+		return ast
+	case NodeQuery, NodeMutation, NodeSubscription:
+		ast["operation"] = n.operationKeyword()
+		definitions := make([]any, len(n.VariableDefinitions))
+		for i, definition := range n.VariableDefinitions {
+			definitions[i] = definition.toAST()
+		}
+		ast["variableDefinitions"] = definitions
+	case NodeFragmentDefinition, NodeInlineFragment:
+		if n.TypeCondition != "" {
+			ast["typeCondition"] = namedTypeAST(n.TypeCondition)
+		}
+	case NodeField, NodeDirective:
+		arguments := make([]any, len(n.Arguments))
+		for i, arg := range n.Arguments {
+			arguments[i] = map[string]any{"kind": "Argument", "name": nameAST(arg.Name), "value": valueAST(arg.Value)}
+		}
+		ast["arguments"] = arguments
+	}
+
+	if n.Name != "" {
+		ast["name"] = nameAST(n.Name)
+	}
+	if n.Alias != "" {
+		ast["alias"] = nameAST(n.Alias)
+	}
+	if n.Type != NodeDirective {
+		ast["directives"] = directivesAST(n.Directives)
+	}
+	if n.Type != NodeDirective && n.Type != NodeFragmentSpread && (n.Type != NodeField || len(n.SelectionSet) > 0) {
+		selections := make([]any, len(n.SelectionSet))
+		for i, selection := range n.SelectionSet {
+			selections[i] = selection.ToAST()
+		}
+		ast["selectionSet"] = map[string]any{"kind": "SelectionSet", "selections": selections}
+	}
+	return ast
+}
+
+// nameAST returns a Name node
+func nameAST(name string) map[string]any {
+	return map[string]any{"kind": "Name", "value": name}
+}
+
+// namedTypeAST returns a NamedType node
+func namedTypeAST(name string) map[string]any {
+	return map[string]any{"kind": "NamedType", "name": nameAST(name)}
+}
+
+// directivesAST converts a directive list
+func directivesAST(directives []*Node) []any {
+	converted := make([]any, len(directives))
+	for i, directive := range directives {
+		converted[i] = directive.ToAST()
+	}
+	return converted
+}
+
+// toAST converts a variable definition
+func (d *VariableDefinition) toAST() map[string]any {
+	ast := map[string]any{
+		"kind":       "VariableDefinition",
+		"variable":   map[string]any{"kind": "Variable", "name": nameAST(d.Name)},
+		"type":       d.Type.toAST(),
+		"directives": directivesAST(d.Directives),
+	}
+	if d.DefaultValue != nil {
+		ast["defaultValue"] = valueAST(d.DefaultValue)
+	}
+	return ast
+}
+
+// toAST converts a type reference into NamedType, ListType, and NonNullType nodes
+func (t *TypeRef) toAST() map[string]any {
+	var ast map[string]any
+	if t.IsList() {
+		ast = map[string]any{"kind": "ListType", "type": t.OfType.toAST()}
+	} else {
+		ast = namedTypeAST(t.Name)
+	}
+	if t.NonNull {
+		ast = map[string]any{"kind": "NonNullType", "type": ast}
+	}
+	return ast
+}
+
+// valueAST converts a value into the matching graphql-js value node
+func valueAST(v Value) map[string]any {
+	switch v := v.(type) {
+	case *StringValue:
+		return map[string]any{"kind": "StringValue", "value": v.Value}
+	case *IntValue:
+		return map[string]any{"kind": "IntValue", "value": v.Raw}
+	case *FloatValue:
+		return map[string]any{"kind": "FloatValue", "value": v.Raw}
+	case *BoolValue:
+		return map[string]any{"kind": "BooleanValue", "value": v.Value}
+	case *EnumValue:
+		return map[string]any{"kind": "EnumValue", "value": v.Value}
+	case *VariableValue:
+		return map[string]any{"kind": "Variable", "name": nameAST(v.Name)}
+	case *ListValue:
+		values := make([]any, len(v.Values))
+		for i, item := range v.Values {
+			values[i] = valueAST(item)
+		}
+		return map[string]any{"kind": "ListValue", "values": values}
+	case *ObjectValue:
+		fields := make([]any, len(v.Fields))
+		for i, field := range v.Fields {
+			fields[i] = map[string]any{"kind": "ObjectField", "name": nameAST(field.Name), "value": valueAST(field.Value)}
+		}
+		return map[string]any{"kind": "ObjectValue", "fields": fields}
+	}
+	return map[string]any{"kind": "NullValue"}
+}
@@ -0,0 +1,66 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+import (
+	"encoding/json"
+	"log"
+	"reflect"
+	"testing"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	log.Println("Starting TestMarshalJSON")
+	query := mustParseQuery(t, `query GetUser($id: ID!) { me: user(id: $id, tags: ["a"]) @include(if: true) { name ... on Admin { role } } }`)
+	want := `{
+		"kind": "OperationDefinition",
+		"operation": "query",
+		"name": {"kind": "Name", "value": "GetUser"},
+		"variableDefinitions": [{
+			"kind": "VariableDefinition",
+			"variable": {"kind": "Variable", "name": {"kind": "Name", "value": "id"}},
+			"type": {"kind": "NonNullType", "type": {"kind": "NamedType", "name": {"kind": "Name", "value": "ID"}}},
+			"directives": []
+		}],
+		"directives": [],
+		"selectionSet": {"kind": "SelectionSet", "selections": [{
+			"kind": "Field",
+			"alias": {"kind": "Name", "value": "me"},
+			"name": {"kind": "Name", "value": "user"},
+			"arguments": [
+				{"kind": "Argument", "name": {"kind": "Name", "value": "id"}, "value": {"kind": "Variable", "name": {"kind": "Name", "value": "id"}}},
+				{"kind": "Argument", "name": {"kind": "Name", "value": "tags"}, "value": {"kind": "ListValue", "values": [{"kind": "StringValue", "value": "a"}]}}
+			],
+			"directives": [{
+				"kind": "Directive",
+				"name": {"kind": "Name", "value": "include"},
+				"arguments": [{"kind": "Argument", "name": {"kind": "Name", "value": "if"}, "value": {"kind": "BooleanValue", "value": true}}]
+			}],
+			"selectionSet": {"kind": "SelectionSet", "selections": [
+				{"kind": "Field", "name": {"kind": "Name", "value": "name"}, "arguments": [], "directives": []},
+				{
+					"kind": "InlineFragment",
+					"typeCondition": {"kind": "NamedType", "name": {"kind": "Name", "value": "Admin"}},
+					"directives": [],
+					"selectionSet": {"kind": "SelectionSet", "selections": [
+						{"kind": "Field", "name": {"kind": "Name", "value": "role"}, "arguments": [], "directives": []}
+					]}
+				}
+			]}
+		}]}
+	}`
+
+	data, err := json.Marshal(query)
+	if err != nil {
+		t.Fatalf("Unexpected marshal error: %s", err)
+	}
+	var got, expected any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Marshalled invalid JSON: %s", err)
+	}
+	if err := json.Unmarshal([]byte(want), &expected); err != nil {
+		t.Fatalf("Invalid expected JSON: %s", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %s, got %s", want, data)
+	}
+}
@@ -0,0 +1,95 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+import "slices"
+
+// Clone returns a deep copy of the tree rooted at n, including arguments and
+// their values, directives, variable definitions, and selection sets, so the
+// copy can be modified without affecting the original
+func (n *Node) Clone() *Node {
+	if n == nil {
+		return nil
+	}
+	clone := *n
+	if n.Arguments != nil {
+		clone.Arguments = make([]Argument, len(n.Arguments))
+		for i, arg := range n.Arguments {
+			clone.Arguments[i] = Argument{Name: arg.Name, Value: cloneValue(arg.Value)}
+		}
+	}
+	clone.Directives = cloneNodes(n.Directives)
+	if n.VariableDefinitions != nil {
+		clone.VariableDefinitions = make([]*VariableDefinition, len(n.VariableDefinitions))
+		for i, definition := range n.VariableDefinitions {
+			clone.VariableDefinitions[i] = &VariableDefinition{
+				Name:         definition.Name,
+				Type:         cloneTypeRef(definition.Type),
+				DefaultValue: cloneValue(definition.DefaultValue),
+				Directives:   cloneNodes(definition.Directives),
+			}
+		}
+	}
+	clone.SelectionSet = cloneNodes(n.SelectionSet)
+	clone.LeadingComments = slices.Clone(n.LeadingComments)
+	return &clone
+}
+
+// cloneNodes returns a deep copy of each node in nodes
+func cloneNodes(nodes []*Node) []*Node {
+	if nodes == nil {
+		return nil
+	}
+	clones := make([]*Node, len(nodes))
+	for i, node := range nodes {
+		clones[i] = node.Clone()
+	}
+	return clones
+}
+
+// cloneValue returns a deep copy of v, including list items and object fields
+func cloneValue(v Value) Value {
+	switch v := v.(type) {
+	case *StringValue:
+		return &StringValue{Value: v.Value}
+	case *IntValue:
+		return &IntValue{Raw: v.Raw}
+	case *FloatValue:
+		return &FloatValue{Raw: v.Raw}
+	case *BoolValue:
+		return &BoolValue{Value: v.Value}
+	case *NullValue:
+		return &NullValue{}
+	case *EnumValue:
+		return &EnumValue{Value: v.Value}
+	case *VariableValue:
+		return &VariableValue{Name: v.Name}
+	case *ListValue:
+		clone := &ListValue{}
+		if v.Values != nil {
+			clone.Values = make([]Value, len(v.Values))
+			for i, item := range v.Values {
+				clone.Values[i] = cloneValue(item)
+			}
+		}
+		return clone
+	case *ObjectValue:
+		clone := &ObjectValue{}
+		if v.Fields != nil {
+			clone.Fields = make([]*ObjectField, len(v.Fields))
+			for i, field := range v.Fields {
+				clone.Fields[i] = &ObjectField{Name: field.Name, Value: cloneValue(field.Value)}
+			}
+		}
+		return clone
+	}
+	// Nil, and values of types outside this package, which cannot be copied
+	return v
+}
+
+// cloneTypeRef returns a deep copy of a type reference
+func cloneTypeRef(t *TypeRef) *TypeRef {
+	if t == nil {
+		return nil
+	}
+	return &TypeRef{Name: t.Name, OfType: cloneTypeRef(t.OfType), NonNull: t.NonNull}
+}
@@ -0,0 +1,87 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+import (
+	"log"
+	"testing"
+)
+
+func TestClone(t *testing.T) {
+	log.Println("Starting TestClone")
+	input := `
+		# Loads a user
+		query Q($id: ID! = "1" @persist, $tags: [String!] = ["a"]) @live {
+			u: user(id: $id, filter: { roles: [ADMIN], meta: { active: true } }) @cache(ttl: 60) {
+				name
+				...Extra @include(if: true)
+				... on Admin { permissions }
+			}
+		}
+	`
+	parse := func() *Node {
+		query, err := NewParserWithOptions(input, Options{KeepComments: true}).ParseQuery()
+		if err != nil {
+			t.Fatalf("Unexpected parse error: %s", err)
+		}
+		return query
+	}
+	original := parse()
+	want := parse()
+
+	if clone := original.Clone(); !compareNodes(clone, original) || clone.String() != original.String() {
+		t.Fatalf("Expected an identical clone, got %s", detailedCompare(clone, original))
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(clone *Node)
+	}{
+		{name: "Operation name", mutate: func(c *Node) { c.Name = "Other" }},
+		{name: "Comments", mutate: func(c *Node) { c.LeadingComments[0] = "changed" }},
+		{name: "Operation directive", mutate: func(c *Node) { c.Directives[0].Name = "defer" }},
+		{name: "Variable default", mutate: func(c *Node) { c.VariableDefinitions[0].DefaultValue.(*StringValue).Value = "2" }},
+		{name: "Variable type", mutate: func(c *Node) { c.VariableDefinitions[1].Type.OfType.NonNull = false }},
+		{name: "Variable directive", mutate: func(c *Node) { c.VariableDefinitions[0].Directives = nil }},
+		{name: "Variable list default", mutate: func(c *Node) {
+			c.VariableDefinitions[1].DefaultValue.(*ListValue).Values[0].(*StringValue).Value = "b"
+		}},
+		{name: "Field alias", mutate: func(c *Node) { c.SelectionSet[0].Alias = "v" }},
+		{name: "Argument name", mutate: func(c *Node) { c.SelectionSet[0].Arguments[0].Name = "key" }},
+		{name: "Argument value", mutate: func(c *Node) { c.SelectionSet[0].Arguments[0].Value.(*VariableValue).Name = "other" }},
+		{name: "List item", mutate: func(c *Node) {
+			c.SelectionSet[0].Arguments[1].Value.(*ObjectValue).Fields[0].Value.(*ListValue).Values[0].(*EnumValue).Value = "USER"
+		}},
+		{name: "Nested object field", mutate: func(c *Node) {
+			c.SelectionSet[0].Arguments[1].Value.(*ObjectValue).Fields[1].Value.(*ObjectValue).Fields[0].Value.(*BoolValue).Value = false
+		}},
+		{name: "Field directive argument", mutate: func(c *Node) {
+			c.SelectionSet[0].Directives[0].Arguments[0].Value.(*IntValue).Raw = "0"
+		}},
+		{name: "Selection appended", mutate: func(c *Node) {
+			c.SelectionSet[0].SelectionSet = append(c.SelectionSet[0].SelectionSet, &Node{Type: NodeField, Name: "email"})
+		}},
+		{name: "Selection replaced", mutate: func(c *Node) { c.SelectionSet[0].SelectionSet[0] = &Node{Type: NodeField, Name: "id"} }},
+		{name: "Spread directive", mutate: func(c *Node) { c.SelectionSet[0].SelectionSet[1].Directives[0].Name = "skip" }},
+		{name: "Inline fragment", mutate: func(c *Node) { c.SelectionSet[0].SelectionSet[2].SelectionSet[0].Name = "roles" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clone := original.Clone()
+			tt.mutate(clone)
+			if compareNodes(clone, want) && clone.LeadingComments[0] == want.LeadingComments[0] {
+				t.Fatalf("Expected the mutation to change the clone")
+			}
+			if !compareNodes(original, want) {
+				t.Errorf("Expected the original to be unchanged, got %s", detailedCompare(original, want))
+			}
+			if original.LeadingComments[0] != want.LeadingComments[0] {
+				t.Errorf("Expected comment %q, got %q", want.LeadingComments[0], original.LeadingComments[0])
+			}
+		})
+	}
+
+	if got := (*Node)(nil).Clone(); got != nil {
+		t.Errorf("Expected nil, got %v", got)
+	}
+}
@@ -0,0 +1,63 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+// ApplyConditionals returns a copy of the node with every selection excluded by
+// @skip(if: ...) or @include(if: ...) removed, resolving variable conditions from
+// vars. A condition whose variable is missing from vars is treated as unknown and
+// leaves the selection in place. The copy shares nothing with the original node,
+// which is not modified.
+func (n *Node) ApplyConditionals(vars map[string]bool) *Node {
+	pruned := n.Clone()
+	pruned.pruneConditionals(vars)
+	return pruned
+}
+
+// pruneConditionals removes the selections excluded by @skip or @include from n and its descendants in place
+func (n *Node) pruneConditionals(vars map[string]bool) {
+	if n == nil {
+		return
+	}
+	var kept []*Node
+	for _, child := range n.SelectionSet {
+		if child.excluded(vars) {
+			continue
+		}
+		child.pruneConditionals(vars)
+		kept = append(kept, child)
+	}
+	n.SelectionSet = kept
+}
+
+// excluded reports whether the node's @skip or @include directives remove it
+func (n *Node) excluded(vars map[string]bool) bool {
+	for _, directive := range n.Directives {
+		condition, known := directive.condition(vars)
+		if !known {
+			continue
+		}
+		switch directive.Name {
+		case "skip":
+			if condition {
+				return true
+			}
+		case "include":
+			if !condition {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// condition resolves a directive's if argument to a boolean, reporting whether it
+// could be resolved from a literal or from vars
+func (n *Node) condition(vars map[string]bool) (value, known bool) {
+	switch arg := n.Argument("if").(type) {
+	case *BoolValue:
+		return arg.Value, true
+	case *VariableValue:
+		value, known = vars[arg.Name]
+		return value, known
+	}
+	return false, false
+}
@@ -0,0 +1,72 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+import (
+	"log"
+	"testing"
+)
+
+func TestApplyConditionals(t *testing.T) {
+	log.Println("Starting TestApplyConditionals")
+	input := `query Q($x: Boolean, $y: Boolean) { user { id email @skip(if: $x) phone @include(if: $y) name @include(if: true) ... on Admin @skip(if: $x) { permissions } } }`
+	tests := []struct {
+		name string
+		vars map[string]bool
+		want string
+	}{
+		{
+			name: "Skip true and include false",
+			vars: map[string]bool{"x": true, "y": false},
+			want: `query Q($x: Boolean, $y: Boolean) { user { id name @include(if: true) } }`,
+		},
+		{
+			name: "Skip false and include true",
+			vars: map[string]bool{"x": false, "y": true},
+			want: `query Q($x: Boolean, $y: Boolean) { user { id email @skip(if: $x) phone @include(if: $y) name @include(if: true) ... on Admin @skip(if: $x) { permissions } } }`,
+		},
+		{
+			name: "Unknown variables keep selections",
+			vars: nil,
+			want: `query Q($x: Boolean, $y: Boolean) { user { id email @skip(if: $x) phone @include(if: $y) name @include(if: true) ... on Admin @skip(if: $x) { permissions } } }`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := mustParseQuery(t, input)
+			if got := query.ApplyConditionals(tt.vars).String(); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+			if got := query.String(); got != mustParseQuery(t, input).String() {
+				t.Errorf("Expected original query to be unchanged, got %s", got)
+			}
+		})
+	}
+}
+
+func TestApplyConditionalsLiterals(t *testing.T) {
+	log.Println("Starting TestApplyConditionalsLiterals")
+	query := mustParseQuery(t, `{ a @skip(if: true) b @include(if: false) c @skip(if: false) @include(if: true) }`)
+
+	if got, want := query.ApplyConditionals(nil).String(), `{ c @skip(if: false) @include(if: true) }`; got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestApplyConditionalsSharesNothing(t *testing.T) {
+	log.Println("Starting TestApplyConditionalsSharesNothing")
+	input := `query Q($v: Int = 1) @live { a(x: 1) { b(y: 2) @include(if: $show) c @skip(if: true) } }`
+	query := mustParseQuery(t, input)
+
+	pruned := query.ApplyConditionals(map[string]bool{"show": true})
+	a := pruned.SelectionSet[0]
+	a.Arguments[0].Value.(*IntValue).Raw = "99"
+	a.SelectionSet[0].Arguments[0].Value.(*IntValue).Raw = "99"
+	a.SelectionSet[0].Directives[0].Name = "skip"
+	pruned.Directives[0].Name = "defer"
+	pruned.VariableDefinitions[0].DefaultValue.(*IntValue).Raw = "99"
+
+	if got, want := query.String(), mustParseQuery(t, input).String(); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
@@ -0,0 +1,183 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+import "strings"
+
+// ChangeKind describes how a part of a query differs between two versions
+type ChangeKind string
+
+// Kinds of change reported by Diff
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeChanged ChangeKind = "changed"
+)
+
+// Change is a single structural difference between two queries. Path names the
+// affected selection by its dotted response keys, such as user.friends.name, with
+// (arg) appended for arguments, @name for directives, and $name for variables.
+// Old and New hold the GraphQL rendering on each side, empty when absent.
+type Change struct {
+	Kind ChangeKind
+	Path string
+	Old  string
+	New  string
+}
+
+// Diff reports the fields, arguments, directives, and variable definitions that
+// were added, removed, or changed going from a to b. Selections are matched by
+// response key, so reordering fields is not a change.
+func Diff(a, b *Node) []Change {
+	var changes []Change
+	diffNodes(a, b, "", &changes)
+	return changes
+}
+
+// diffNodes appends the differences between two nodes matched at path
+func diffNodes(a, b *Node, path string, changes *[]Change) {
+	if a.Type != b.Type || a.Name != b.Name || a.TypeCondition != b.TypeCondition {
+		*changes = append(*changes, Change{Kind: ChangeChanged, Path: path, Old: nodeHeader(a), New: nodeHeader(b)})
+	}
+	diffVariableDefinitions(a.VariableDefinitions, b.VariableDefinitions, path, changes)
+	diffArguments(a.Arguments, b.Arguments, path, changes)
+	diffDirectives(a.Directives, b.Directives, path, changes)
+	diffSelections(a.SelectionSet, b.SelectionSet, path, changes)
+}
+
+// nodeHeader renders the identifying part of a node, such as alias: name for a
+// field or query Name for an operation, for reporting a changed node
+func nodeHeader(n *Node) string {
+	switch n.Type {
+	case NodeQuery, NodeMutation, NodeSubscription:
+		return strings.TrimSpace(n.operationKeyword() + " " + n.Name)
+	case NodeFragmentDefinition:
+		return "fragment " + n.Name + " on " + n.TypeCondition
+	case NodeInlineFragment, NodeFragmentSpread:
+		return selectionKey(n)
+	}
+	if n.Alias != "" {
+		return n.Alias + ": " + n.Name
+	}
+	return n.Name
+}
+
+// diffVariableDefinitions appends changes between two operations' variable definitions
+func diffVariableDefinitions(a, b []*VariableDefinition, path string, changes *[]Change) {
+	find := func(definitions []*VariableDefinition, name string) *VariableDefinition {
+		for _, definition := range definitions {
+			if definition.Name == name {
+				return definition
+			}
+		}
+		return nil
+	}
+	for _, old := range a {
+		p := joinPath(path, "$"+old.Name)
+		if updated := find(b, old.Name); updated == nil {
+			*changes = append(*changes, Change{Kind: ChangeRemoved, Path: p, Old: old.String()})
+		} else if old.String() != updated.String() {
+			*changes = append(*changes, Change{Kind: ChangeChanged, Path: p, Old: old.String(), New: updated.String()})
+		}
+	}
+	for _, added := range b {
+		if find(a, added.Name) == nil {
+			*changes = append(*changes, Change{Kind: ChangeAdded, Path: joinPath(path, "$"+added.Name), New: added.String()})
+		}
+	}
+}
+
+// diffArguments appends changes between two argument lists, matched by name
+func diffArguments(a, b []Argument, path string, changes *[]Change) {
+	for _, old := range a {
+		p := path + "(" + old.Name + ")"
+		updated := findArgument(b, old.Name)
+		if updated == nil {
+			*changes = append(*changes, Change{Kind: ChangeRemoved, Path: p, Old: old.Value.String()})
+		} else if !valuesEqual(old.Value, updated) {
+			*changes = append(*changes, Change{Kind: ChangeChanged, Path: p, Old: old.Value.String(), New: updated.String()})
+		}
+	}
+	for _, added := range b {
+		if findArgument(a, added.Name) == nil {
+			*changes = append(*changes, Change{Kind: ChangeAdded, Path: path + "(" + added.Name + ")", New: added.Value.String()})
+		}
+	}
+}
+
+// diffDirectives appends changes between two directive lists, matched by name
+func diffDirectives(a, b []*Node, path string, changes *[]Change) {
+	for _, old := range a {
+		p := path + "@" + old.Name
+		if updated := findDirective(b, old.Name); updated == nil {
+			*changes = append(*changes, Change{Kind: ChangeRemoved, Path: p, Old: old.String()})
+		} else {
+			diffArguments(old.Arguments, updated.Arguments, p, changes)
+		}
+	}
+	for _, added := range b {
+		if findDirective(a, added.Name) == nil {
+			*changes = append(*changes, Change{Kind: ChangeAdded, Path: path + "@" + added.Name, New: added.String()})
+		}
+	}
+}
+
+// findDirective returns the first directive with the given name, or nil
+func findDirective(directives []*Node, name string) *Node {
+	for _, directive := range directives {
+		if directive.Name == name {
+			return directive
+		}
+	}
+	return nil
+}
+
+// diffSelections appends changes between two selection sets, matched by selection key
+func diffSelections(a, b []*Node, path string, changes *[]Change) {
+	for _, old := range a {
+		p := joinPath(path, selectionKey(old))
+		if updated := findSelection(b, selectionKey(old)); updated == nil {
+			*changes = append(*changes, Change{Kind: ChangeRemoved, Path: p, Old: old.String()})
+		} else {
+			diffNodes(old, updated, p, changes)
+		}
+	}
+	for _, added := range b {
+		if findSelection(a, selectionKey(added)) == nil {
+			*changes = append(*changes, Change{Kind: ChangeAdded, Path: joinPath(path, selectionKey(added)), New: added.String()})
+		}
+	}
+}
+
+// selectionKey identifies a selection within its selection set: the response key
+// for fields, ...Name for spreads, and ... on Type for inline fragments
+func selectionKey(n *Node) string {
+	switch n.Type {
+	case NodeFragmentSpread:
+		return "..." + n.Name
+	case NodeInlineFragment:
+		if n.TypeCondition == "" {
+			return "..."
+		}
+		return "... on " + n.TypeCondition
+	default:
+		return n.ResponseKey()
+	}
+}
+
+// findSelection returns the selection with the given key, or nil
+func findSelection(selections []*Node, key string) *Node {
+	for _, selection := range selections {
+		if selectionKey(selection) == key {
+			return selection
+		}
+	}
+	return nil
+}
+
+// joinPath appends a path segment using a dot separator
+func joinPath(path, segment string) string {
+	if path == "" {
+		return segment
+	}
+	return path + "." + segment
+}
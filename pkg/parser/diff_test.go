@@ -0,0 +1,79 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+import (
+	"log"
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	log.Println("Starting TestDiff")
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want []Change
+	}{
+		{
+			name: "Identical apart from field order",
+			a:    `query Q { user { id name } }`,
+			b:    `query Q { user { name id } }`,
+			want: nil,
+		},
+		{
+			name: "Added field",
+			a:    `query Q { user { friends { id } } }`,
+			b:    `query Q { user { friends { id name } } }`,
+			want: []Change{{Kind: ChangeAdded, Path: "user.friends.name", New: "name"}},
+		},
+		{
+			name: "Removed field",
+			a:    `query Q { user { id email { address } } }`,
+			b:    `query Q { user { id } }`,
+			want: []Change{{Kind: ChangeRemoved, Path: "user.email", Old: "email { address }"}},
+		},
+		{
+			name: "Changed argument value",
+			a:    `query Q { user(id: "1") { posts(first: 10) { title } } }`,
+			b:    `query Q { user(id: "1") { posts(first: 20) { title } } }`,
+			want: []Change{{Kind: ChangeChanged, Path: "user.posts(first)", Old: "10", New: "20"}},
+		},
+		{
+			name: "Arguments and directives added and removed",
+			a:    `{ user(id: 1) @cache(ttl: 60) { id } }`,
+			b:    `{ user(login: "ada") @cache(ttl: 30) @live { id } }`,
+			want: []Change{
+				{Kind: ChangeRemoved, Path: "user(id)", Old: "1"},
+				{Kind: ChangeAdded, Path: "user(login)", New: `"ada"`},
+				{Kind: ChangeChanged, Path: "user@cache(ttl)", Old: "60", New: "30"},
+				{Kind: ChangeAdded, Path: "user@live", New: "@live"},
+			},
+		},
+		{
+			name: "Aliased field points at a different field",
+			a:    `{ me: viewer { id } }`,
+			b:    `{ me: user { id } }`,
+			want: []Change{{Kind: ChangeChanged, Path: "me", Old: "me: viewer", New: "me: user"}},
+		},
+		{
+			name: "Variables and fragments",
+			a:    `query Q($id: ID) { node(id: $id) { ...A ... on User { name } } }`,
+			b:    `query Q($id: ID!) { node(id: $id) { ... on User { name email } } }`,
+			want: []Change{
+				{Kind: ChangeChanged, Path: "$id", Old: "$id: ID", New: "$id: ID!"},
+				{Kind: ChangeRemoved, Path: "node....A", Old: "...A"},
+				{Kind: ChangeAdded, Path: "node.... on User.email", New: "email"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Diff(mustParseQuery(t, tt.a), mustParseQuery(t, tt.b))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+// NodesEqual reports whether two trees are structurally equal. Arguments are
+// compared as a set, so their order does not affect equality, while directives,
+// variable definitions, and selections must appear in the same order.
+func NodesEqual(a, b *Node) bool {
+	return equalNodes(a, b, nil)
+}
+
+// DefaultPaginationArgs lists the argument names treated as pagination cursors
+var DefaultPaginationArgs = []string{"first", "last", "after", "before"}
+
+// EqualIgnoringPagination reports whether two nodes are structurally equal,
+// treating the values of DefaultPaginationArgs as wildcards
+func EqualIgnoringPagination(a, b *Node) bool {
+	return EqualIgnoringArgs(a, b, DefaultPaginationArgs...)
+}
+
+// EqualIgnoringArgs reports whether two nodes are structurally equal, treating the
+// values of the named arguments as wildcards. The arguments themselves must still
+// be present on both nodes; only their values are ignored. Argument order does not
+// affect equality.
+func EqualIgnoringArgs(a, b *Node, ignored ...string) bool {
+	skip := make(map[string]bool, len(ignored))
+	for _, name := range ignored {
+		skip[name] = true
+	}
+	return equalNodes(a, b, skip)
+}
+
+// equalNodes compares two nodes recursively, ignoring the values of arguments in skip
+func equalNodes(a, b *Node, skip map[string]bool) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Type != b.Type || a.Name != b.Name || a.Alias != b.Alias || a.TypeCondition != b.TypeCondition {
+		return false
+	}
+
+	if len(a.Arguments) != len(b.Arguments) {
+		return false
+	}
+	for _, arg := range a.Arguments {
+		other := b.Argument(arg.Name)
+		if other == nil {
+			return false
+		}
+		if !skip[arg.Name] && !valuesEqual(other, arg.Value) {
+			return false
+		}
+	}
+
+	if !variableDefinitionsEqual(a.VariableDefinitions, b.VariableDefinitions) {
+		return false
+	}
+
+	if len(a.Directives) != len(b.Directives) {
+		return false
+	}
+	for i := range a.Directives {
+		if !equalNodes(a.Directives[i], b.Directives[i], skip) {
+			return false
+		}
+	}
+
+	if len(a.SelectionSet) != len(b.SelectionSet) {
+		return false
+	}
+	for i := range a.SelectionSet {
+		if !equalNodes(a.SelectionSet[i], b.SelectionSet[i], skip) {
+			return false
+		}
+	}
+	return true
+}
+
+// variableDefinitionsEqual reports whether two operations declare the same
+// variables, in the same order, with the same types, defaults, and directives
+func variableDefinitionsEqual(a, b []*VariableDefinition) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].String() != b[i].String() {
+			return false
+		}
+	}
+	return true
+}
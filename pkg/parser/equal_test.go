@@ -0,0 +1,151 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+import (
+	"log"
+	"testing"
+)
+
+func TestEqualIgnoringPagination(t *testing.T) {
+	log.Println("Starting TestEqualIgnoringPagination")
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want bool
+	}{
+		{
+			name: "Different after cursors",
+			a:    `query Feed { posts(after: "abc") { title } }`,
+			b:    `query Feed { posts(after: "xyz") { title } }`,
+			want: true,
+		},
+		{
+			name: "Different field sets",
+			a:    `query Feed { posts(after: "abc") { title } }`,
+			b:    `query Feed { posts(after: "abc") { title body } }`,
+			want: false,
+		},
+		{
+			name: "Different non-pagination argument",
+			a:    `query Feed { posts(author: "alice") { title } }`,
+			b:    `query Feed { posts(author: "bob") { title } }`,
+			want: false,
+		},
+		{
+			name: "Pagination argument missing on one side",
+			a:    `query Feed { posts(after: "abc") { title } }`,
+			b:    `query Feed { posts { title } }`,
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := mustParseQuery(t, tt.a)
+			b := mustParseQuery(t, tt.b)
+			if got := EqualIgnoringPagination(a, b); got != tt.want {
+				t.Errorf("EqualIgnoringPagination() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEqualIgnoringArgsCustomNames(t *testing.T) {
+	log.Println("Starting TestEqualIgnoringArgsCustomNames")
+	a := mustParseQuery(t, `query Feed { posts(page: "1") { title } }`)
+	b := mustParseQuery(t, `query Feed { posts(page: "2") { title } }`)
+
+	if EqualIgnoringPagination(a, b) {
+		t.Errorf("Expected page to be compared by default")
+	}
+	if !EqualIgnoringArgs(a, b, "page") {
+		t.Errorf("Expected page to be ignored when configured")
+	}
+}
+
+func TestEqualIgnoringArgsOrder(t *testing.T) {
+	log.Println("Starting TestEqualIgnoringArgsOrder")
+	a := mustParseQuery(t, `query Feed { posts(first: 10, author: "alice") { title } }`)
+	b := mustParseQuery(t, `query Feed { posts(author: "alice", first: 20) { title } }`)
+
+	if !EqualIgnoringPagination(a, b) {
+		t.Errorf("Expected argument order to be ignored")
+	}
+}
+
+func TestNodesEqual(t *testing.T) {
+	log.Println("Starting TestNodesEqual")
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want bool
+	}{
+		{
+			name: "Identical",
+			a:    `query Q { user(id: 1) { name } }`,
+			b:    `query Q { user(id: 1) { name } }`,
+			want: true,
+		},
+		{
+			name: "Reordered field arguments",
+			a:    `{ posts(first: 10, author: "alice", sort: DESC) { title } }`,
+			b:    `{ posts(sort: DESC, first: 10, author: "alice") { title } }`,
+			want: true,
+		},
+		{
+			name: "Reordered directive arguments",
+			a:    `{ posts @cache(ttl: 60, scope: PRIVATE) { title } }`,
+			b:    `{ posts @cache(scope: PRIVATE, ttl: 60) { title } }`,
+			want: true,
+		},
+		{
+			name: "Reordered nested arguments",
+			a:    `{ user { posts(first: 1, after: "x") { id } } }`,
+			b:    `{ user { posts(after: "x", first: 1) { id } } }`,
+			want: true,
+		},
+		{
+			name: "Different argument value",
+			a:    `{ posts(first: 10) { title } }`,
+			b:    `{ posts(first: 20) { title } }`,
+		},
+		{
+			name: "Missing argument",
+			a:    `{ posts(first: 10, author: "alice") { title } }`,
+			b:    `{ posts(first: 10) { title } }`,
+		},
+		{
+			name: "Reordered selections",
+			a:    `{ user { id name } }`,
+			b:    `{ user { name id } }`,
+		},
+		{
+			name: "Reordered directives",
+			a:    `{ user @a @b { id } }`,
+			b:    `{ user @b @a { id } }`,
+		},
+		{
+			name: "Different alias",
+			a:    `{ me: user { id } }`,
+			b:    `{ user { id } }`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := mustParseQuery(t, tt.a), mustParseQuery(t, tt.b)
+			if got := NodesEqual(a, b); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+			if got := NodesEqual(b, a); got != tt.want {
+				t.Errorf("Expected %v with the nodes swapped, got %v", tt.want, got)
+			}
+		})
+	}
+
+	if !NodesEqual(nil, nil) || NodesEqual(nil, mustParseQuery(t, `{ a }`)) {
+		t.Errorf("Expected only two nil nodes to be equal")
+	}
+}
@@ -0,0 +1,87 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tom/graphqlinsights/pkg/lexer"
+)
+
+// snippetContext is the number of bytes of input kept on each side of an error
+const snippetContext = 20
+
+// ParseError describes why and where parsing failed
+type ParseError struct {
+	Message string
+	Line    int
+	Column  int
+	Snippet string // Input surrounding the offending token, on a single line
+}
+
+// Error returns the error message including the position of the offending token
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s at line %d, column %d", e.Message, e.Line, e.Column)
+}
+
+// fail aborts parsing with a ParseError positioned at the current token. When the
+// current token is illegal, the lexer's description of it is reported instead,
+// since it is the underlying cause of any failure there.
+func (p *Parser) fail(format string, args ...any) {
+	if p.curr.Type == lexer.TokenIllegal {
+		format, args = "Illegal token: %s", []any{p.curr.Value}
+	}
+	panic(&ParseError{
+		Message: fmt.Sprintf(format, args...),
+		Line:    p.curr.Line,
+		Column:  p.curr.Column,
+		Snippet: snippet(p.input, p.curr.Offset),
+	})
+}
+
+// snippet returns up to snippetContext bytes of input on each side of offset,
+// trimmed to whole characters, with line breaks and tabs replaced by spaces
+func snippet(input string, offset int) string {
+	start := max(min(offset, len(input))-snippetContext, 0)
+	end := min(offset+snippetContext, len(input))
+	for start < end && !utf8.RuneStart(input[start]) {
+		start++
+	}
+	for end < len(input) && end > start && !utf8.RuneStart(input[end]) {
+		end--
+	}
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\r' || r == '\t' {
+			return ' '
+		}
+		return r
+	}, input[start:end])
+}
+
+// contextError carries the error of a done context out of the parser through a panic
+type contextError struct {
+	err error
+}
+
+// recoverError converts a ParseError or context error raised while parsing into
+// a returned error. Any other panic is propagated unchanged.
+func recoverError(err *error) {
+	if r := recover(); r != nil {
+		switch e := r.(type) {
+		case *ParseError:
+			*err = e
+		case contextError:
+			*err = e.err
+		default:
+			panic(r)
+		}
+	}
+}
+
+// Errors returned when fragment spreads cannot be resolved
+var (
+	ErrUnknownFragment = errors.New("unknown fragment")
+	ErrCyclicFragment  = errors.New("cyclic fragment")
+)
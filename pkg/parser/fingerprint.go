@@ -0,0 +1,61 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// literalPlaceholder replaces argument literals in a normalized query
+const literalPlaceholder = "?"
+
+// Fingerprint returns a stable hash of the node's structure with argument literals
+// replaced by placeholders and arguments sorted by name, so queries that differ
+// only in literal values or argument order share a fingerprint. Variables,
+// aliases, names, directives, and selections all remain part of the signature.
+func (n *Node) Fingerprint() string {
+	sum := sha256.Sum256([]byte(sortArguments(n.Normalize()).String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// Normalize returns a copy of the node with every argument literal replaced by a
+// ? placeholder. Object values keep their field names; list values collapse to a
+// single placeholder since their length is data rather than structure. The copy
+// shares nothing with the original node, which is not modified.
+func (n *Node) Normalize() *Node {
+	normalized := n.Clone()
+	normalized.normalizeArguments()
+	return normalized
+}
+
+// normalizeArguments replaces the argument literals of n, its directives, and its selections in place
+func (n *Node) normalizeArguments() {
+	if n == nil {
+		return
+	}
+	for i, arg := range n.Arguments {
+		n.Arguments[i].Value = normalizeValue(arg.Value)
+	}
+	for _, directive := range n.Directives {
+		directive.normalizeArguments()
+	}
+	for _, child := range n.SelectionSet {
+		child.normalizeArguments()
+	}
+}
+
+// normalizeValue returns a new placeholder in place of a literal v, keeping
+// variables and normalizing the fields of object values in place
+func normalizeValue(v Value) Value {
+	switch v := v.(type) {
+	case *VariableValue:
+		return v
+	case *ObjectValue:
+		for _, field := range v.Fields {
+			field.Value = normalizeValue(field.Value)
+		}
+		return v
+	default:
+		return &EnumValue{Value: literalPlaceholder}
+	}
+}
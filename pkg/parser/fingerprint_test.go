@@ -0,0 +1,115 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+import (
+	"log"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	log.Println("Starting TestFingerprint")
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		same bool
+	}{
+		{
+			name: "Different string literal",
+			a:    `query GetUser { user(id: "1") { name } }`,
+			b:    `query GetUser { user(id: "2") { name } }`,
+			same: true,
+		},
+		{
+			name: "Different literal kinds and lists",
+			a:    `{ posts(first: 10, tags: ["a"], filter: { status: ACTIVE }) { title } }`,
+			b:    `{ posts(first: 25, tags: ["b", "c"], filter: { status: DRAFT }) { title } }`,
+			same: true,
+		},
+		{
+			name: "Different directive literal",
+			a:    `{ avatar @cache(ttl: 60) }`,
+			b:    `{ avatar @cache(ttl: 300) }`,
+			same: true,
+		},
+		{
+			name: "Different field selection",
+			a:    `query GetUser { user(id: "1") { name } }`,
+			b:    `query GetUser { user(id: "1") { email } }`,
+			same: false,
+		},
+		{
+			name: "Different argument name",
+			a:    `{ user(id: "1") { name } }`,
+			b:    `{ user(login: "1") { name } }`,
+			same: false,
+		},
+		{
+			name: "Literal versus variable",
+			a:    `{ user(id: "1") { name } }`,
+			b:    `{ user(id: $id) { name } }`,
+			same: false,
+		},
+		{
+			name: "Different object field names",
+			a:    `{ posts(filter: { status: ACTIVE }) { title } }`,
+			b:    `{ posts(filter: { author: ACTIVE }) { title } }`,
+			same: false,
+		},
+		{
+			name: "Different argument order",
+			a:    `{ f(a: 1, b: 2) @cache(ttl: 60, scope: PUBLIC) }`,
+			b:    `{ f(b: 2, a: 1) @cache(scope: PUBLIC, ttl: 60) }`,
+			same: true,
+		},
+		{
+			name: "Different object field order",
+			a:    `{ posts(filter: { status: ACTIVE, author: $id }) { title } }`,
+			b:    `{ posts(filter: { author: $id, status: DRAFT }) { title } }`,
+			same: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := mustParseQuery(t, tt.a).Fingerprint()
+			b := mustParseQuery(t, tt.b).Fingerprint()
+			if (a == b) != tt.same {
+				t.Errorf("Expected same fingerprint = %v, got %s and %s", tt.same, a, b)
+			}
+		})
+	}
+}
+
+func TestNormalizeLeavesOriginalUntouched(t *testing.T) {
+	log.Println("Starting TestNormalizeLeavesOriginalUntouched")
+	query := mustParseQuery(t, `{ user(id: "1") @cache(ttl: 60) { name } }`)
+
+	if got, want := query.Normalize().String(), `{ user(id: ?) @cache(ttl: ?) { name } }`; got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+	if got, want := query.String(), `{ user(id: "1") @cache(ttl: 60) { name } }`; got != want {
+		t.Errorf("Expected original %s, got %s", want, got)
+	}
+}
+
+func TestNormalizeSharesNothing(t *testing.T) {
+	log.Println("Starting TestNormalizeSharesNothing")
+	input := `query Q($id: ID = "1") { user(id: $id, filter: {role: ADMIN}) @cache(ttl: 60) { name } }`
+	query := mustParseQuery(t, input)
+
+	normalized := query.Normalize()
+	user := normalized.SelectionSet[0]
+	user.Argument("id").(*VariableValue).Name = "other"
+	user.Argument("filter").(*ObjectValue).Fields[0].Value.(*EnumValue).Value = "LEAK"
+	user.Directives[0].Arguments[0].Value.(*EnumValue).Value = "LEAK"
+	normalized.VariableDefinitions[0].DefaultValue.(*StringValue).Value = "2"
+
+	if got, want := query.String(), mustParseQuery(t, input).String(); got != want {
+		t.Errorf("Expected original %s, got %s", want, got)
+	}
+	want := `query Q($id: ID = "1") { user(id: $id, filter: {role: ?}) @cache(ttl: ?) { name } }`
+	if got := query.Normalize().String(); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
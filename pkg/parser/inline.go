@@ -0,0 +1,115 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// InlineFragments returns a copy of the document's operations with every fragment
+// spread replaced by the fields of the fragment it references, so the result no
+// longer depends on fragment definitions. Each spread becomes an inline fragment
+// carrying the fragment's type condition and the spread's directives, which keeps
+// the meaning of the query unchanged. It returns an error wrapping
+// ErrUnknownFragment or ErrCyclicFragment if a spread cannot be resolved. The
+// result shares nothing with the document, which is not modified.
+func InlineFragments(doc *Node) (*Node, error) {
+	fragments := fragmentDefinitions(doc)
+
+	inlined := &Node{Type: NodeDocument}
+	for _, operation := range doc.Operations() {
+		operationCopy := operation.Clone()
+		if err := inlineSelections(operationCopy.SelectionSet, fragments, nil); err != nil {
+			return nil, err
+		}
+		inlined.SelectionSet = append(inlined.SelectionSet, operationCopy)
+	}
+	return inlined, nil
+}
+
+// inlineSelections expands the spreads of a cloned selection set in place, giving
+// each expansion its own copy of the fragment's fields; stack holds the fragments
+// currently being expanded so cycles can be reported
+func inlineSelections(selections []*Node, fragments map[string]*Node, stack []string) error {
+	for i, selection := range selections {
+		if selection.Type != NodeFragmentSpread {
+			if err := inlineSelections(selection.SelectionSet, fragments, stack); err != nil {
+				return err
+			}
+			continue
+		}
+
+		fragment, ok := fragments[selection.Name]
+		if !ok {
+			return fmt.Errorf("%w %s", ErrUnknownFragment, selection.Name)
+		}
+		for j, name := range stack {
+			if name == selection.Name {
+				cycle := append(append([]string(nil), stack[j:]...), name)
+				return fmt.Errorf("%w: %s", ErrCyclicFragment, strings.Join(cycle, " -> "))
+			}
+		}
+		children := cloneNodes(fragment.SelectionSet)
+		if err := inlineSelections(children, fragments, append(stack, selection.Name)); err != nil {
+			return err
+		}
+		selections[i] = &Node{
+			Type:          NodeInlineFragment,
+			TypeCondition: fragment.TypeCondition,
+			Directives:    selection.Directives,
+			SelectionSet:  children,
+		}
+	}
+	return nil
+}
+
+// ValidateFragments checks that no fragment definition in the document spreads
+// itself, directly or through other fragments, including fragments no operation
+// uses. It returns an error wrapping ErrCyclicFragment that names the first cycle
+// found, such as A -> B -> A. Spreads of unknown fragments are left for
+// InlineFragments to report.
+func ValidateFragments(doc *Node) error {
+	var order []string
+	references := make(map[string][]string)
+	for _, definition := range doc.SelectionSet {
+		if definition.Type != NodeFragmentDefinition {
+			continue
+		}
+		order = append(order, definition.Name)
+		Walk(definition, func(n *Node) bool {
+			if n.Type == NodeFragmentSpread && !slices.Contains(references[definition.Name], n.Name) {
+				references[definition.Name] = append(references[definition.Name], n.Name)
+			}
+			return true
+		})
+	}
+
+	done := make(map[string]bool)
+	var stack []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		if i := slices.Index(stack, name); i >= 0 {
+			cycle := append(append([]string(nil), stack[i:]...), name)
+			return fmt.Errorf("%w: %s", ErrCyclicFragment, strings.Join(cycle, " -> "))
+		}
+		if done[name] {
+			return nil
+		}
+		stack = append(stack, name)
+		for _, referenced := range references[name] {
+			if err := visit(referenced); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		done[name] = true
+		return nil
+	}
+	for _, name := range order {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
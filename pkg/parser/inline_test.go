@@ -0,0 +1,172 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestInlineFragments(t *testing.T) {
+	log.Println("Starting TestInlineFragments")
+	document, err := NewParser(`
+		query Q { user { id ...UserFields @include(if: $full) } }
+		fragment UserFields on User { name ...Contact }
+		fragment Contact on User { email }
+	`).ParseDocument()
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+
+	inlined, err := InlineFragments(document)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	want := `query Q { user { id ... on User @include(if: $full) { name ... on User { email } } } }`
+	if got := inlined.String(); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+	if got := document.SelectionSet[0].SelectionSet[0].SelectionSet[1].Type; got != NodeFragmentSpread {
+		t.Errorf("Expected the original document to keep its spread, got %s", got)
+	}
+}
+
+func TestInlineFragmentsErrors(t *testing.T) {
+	log.Println("Starting TestInlineFragmentsErrors")
+	tests := []struct {
+		name    string
+		input   string
+		wantErr error
+		wantMsg string
+	}{
+		{
+			name: "Cyclic fragments",
+			input: `
+				{ user { ...A } }
+				fragment A on User { friends { ...B } }
+				fragment B on User { ...A }
+			`,
+			wantErr: ErrCyclicFragment,
+			wantMsg: "A -> B -> A",
+		},
+		{
+			name:    "Unknown fragment",
+			input:   `{ user { ...Missing } }`,
+			wantErr: ErrUnknownFragment,
+			wantMsg: "Missing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			document, err := NewParser(tt.input).ParseDocument()
+			if err != nil {
+				t.Fatalf("Unexpected parse error: %s", err)
+			}
+			_, err = InlineFragments(document)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected %v, got %v", tt.wantErr, err)
+			}
+			if !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("Expected error to mention %q, got %s", tt.wantMsg, err)
+			}
+		})
+	}
+}
+
+func TestValidateFragments(t *testing.T) {
+	log.Println("Starting TestValidateFragments")
+	tests := []struct {
+		name    string
+		input   string
+		wantMsg string
+	}{
+		{
+			name: "Valid chain",
+			input: `
+				{ user { ...A } }
+				fragment A on User { friends { ...B } ...C }
+				fragment B on User { ...C }
+				fragment C on User { name }
+			`,
+		},
+		{
+			name: "Direct cycle",
+			input: `
+				{ user { id } }
+				fragment A on User { friends { ...A } }
+			`,
+			wantMsg: "cyclic fragment: A -> A",
+		},
+		{
+			name: "Indirect cycle in unused fragments",
+			input: `
+				{ user { ...Leaf } }
+				fragment Leaf on User { name }
+				fragment A on User { ... on Admin { ...B } }
+				fragment B on User { ...Leaf ...C }
+				fragment C on User { ...A }
+			`,
+			wantMsg: "cyclic fragment: A -> B -> C -> A",
+		},
+		{
+			name: "Unknown fragment is not a cycle",
+			input: `
+				{ user { ...A } }
+				fragment A on User { ...Missing }
+			`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			document, err := NewParser(tt.input).ParseDocument()
+			if err != nil {
+				t.Fatalf("Unexpected parse error: %s", err)
+			}
+			err = ValidateFragments(document)
+			if tt.wantMsg == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %s", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrCyclicFragment) || err.Error() != tt.wantMsg {
+				t.Errorf("Expected %q wrapping ErrCyclicFragment, got %v", tt.wantMsg, err)
+			}
+		})
+	}
+}
+
+func TestInlineFragmentsSharesNothing(t *testing.T) {
+	log.Println("Starting TestInlineFragmentsSharesNothing")
+	input := `query Q($id: ID) @cache(ttl: 1) { user(id: $id) { ...A @include(if: $full) ...A } } fragment A on User { friends(first: 10) { name } }`
+	document, err := NewParser(input).ParseDocument()
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+	original := document.String()
+
+	inlined, err := InlineFragments(document)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	operation := inlined.SelectionSet[0]
+	operation.Directives[0].Argument("ttl").(*IntValue).Raw = "2"
+	operation.VariableDefinitions[0].Name = "changed"
+	user := operation.SelectionSet[0]
+	user.Arguments[0].Name = "changed"
+	first := user.SelectionSet[0]
+	first.Directives[0].Argument("if").(*VariableValue).Name = "changed"
+	first.SelectionSet[0].Argument("first").(*IntValue).Raw = "99"
+	first.SelectionSet[0].SelectionSet[0].Name = "changed"
+
+	if got := document.String(); got != original {
+		t.Errorf("Expected the document to be unmodified, got %s", got)
+	}
+	want := `query Q($changed: ID) @cache(ttl: 2) { user(changed: $id) { ... on User @include(if: $changed) { friends(first: 99) { changed } } ... on User { friends(first: 10) { name } } } }`
+	if got := inlined.String(); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
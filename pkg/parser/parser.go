@@ -2,6 +2,7 @@
 package parser
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -13,32 +14,172 @@ type NodeType string
 
 // Node types for GraphQL query parsing
 const (
-	NodeQuery     NodeType = "Query"
-	NodeField     NodeType = "Field"
-	NodeDirective NodeType = "Directive" // Node type for directives
+	NodeQuery              NodeType = "Query"
+	NodeMutation           NodeType = "Mutation"
+	NodeSubscription       NodeType = "Subscription"
+	NodeField              NodeType = "Field"
+	NodeDirective          NodeType = "Directive" // Node type for directives
+	NodeFragmentDefinition NodeType = "FragmentDefinition"
+	NodeFragmentSpread     NodeType = "FragmentSpread"
+	NodeInlineFragment     NodeType = "InlineFragment"
+	NodeDocument           NodeType = "Document" // Top-level definitions are held in SelectionSet
+)
+
+// DirectiveLocation represents where in a document a directive was applied
+type DirectiveLocation string
+
+// Directive locations as named by the GraphQL specification
+const (
+	LocationQuery              DirectiveLocation = "QUERY"
+	LocationMutation           DirectiveLocation = "MUTATION"
+	LocationSubscription       DirectiveLocation = "SUBSCRIPTION"
+	LocationField              DirectiveLocation = "FIELD"
+	LocationFragmentDefinition DirectiveLocation = "FRAGMENT_DEFINITION"
+	LocationFragmentSpread     DirectiveLocation = "FRAGMENT_SPREAD"
+	LocationInlineFragment     DirectiveLocation = "INLINE_FRAGMENT"
+	LocationVariableDefinition DirectiveLocation = "VARIABLE_DEFINITION"
 )
 
 // Node represents a node in the GraphQL AST
 type Node struct {
-	Type         NodeType
-	Name         string
-	Arguments    map[string]string
-	Directives   []*Node // Field for directives
-	SelectionSet []*Node
+	Type          NodeType
+	Name          string
+	Alias         string            // Alias a field was requested under, if any
+	TypeCondition string            // Type named after "on" in fragment definitions and inline fragments
+	OperationType string            // Operation keyword: query, mutation, or subscription; empty for other nodes
+	Arguments     []Argument        // Arguments in source order
+	Directives    []*Node           // Field for directives
+	Location      DirectiveLocation // Location a directive node was applied at
+	SelectionSet  []*Node
+
+	VariableDefinitions []*VariableDefinition // Variables declared by an operation
+
+	// LeadingComments holds the text after # of the comments directly preceding an
+	// operation, fragment definition, field, or fragment spread, when the parser was
+	// created with Options.KeepComments
+	LeadingComments []string
+}
+
+// operationKinds maps each operation keyword to its node type and directive location
+var operationKinds = map[string]struct {
+	nodeType NodeType
+	location DirectiveLocation
+}{
+	"query":        {NodeQuery, LocationQuery},
+	"mutation":     {NodeMutation, LocationMutation},
+	"subscription": {NodeSubscription, LocationSubscription},
+}
+
+// typeSystemKeywords are the keywords that start a type system definition or
+// extension, which describes a schema (SDL) rather than a request
+var typeSystemKeywords = map[string]bool{
+	"schema":    true,
+	"scalar":    true,
+	"type":      true,
+	"interface": true,
+	"union":     true,
+	"enum":      true,
+	"input":     true,
+	"directive": true,
+	"extend":    true,
+}
+
+// IsOperation reports whether the node is a query, mutation, or subscription
+func (n *Node) IsOperation() bool {
+	return n.Type == NodeQuery || n.Type == NodeMutation || n.Type == NodeSubscription
+}
+
+// Operations returns the operations defined in a document node, skipping fragment definitions
+func (n *Node) Operations() []*Node {
+	var operations []*Node
+	for _, definition := range n.SelectionSet {
+		if definition.IsOperation() {
+			operations = append(operations, definition)
+		}
+	}
+	return operations
+}
+
+// Argument returns the value of the named argument, or nil if the node has no such argument
+func (n *Node) Argument(name string) Value {
+	return findArgument(n.Arguments, name)
+}
+
+// findArgument returns the value of the named argument in args, or nil
+func findArgument(args []Argument, name string) Value {
+	for _, arg := range args {
+		if arg.Name == name {
+			return arg.Value
+		}
+	}
+	return nil
+}
+
+// OperationNames returns the name of each operation in a document node in source
+// order, using an empty string for anonymous operations and the { ... } shorthand
+func (n *Node) OperationNames() []string {
+	operations := n.Operations()
+	names := make([]string, len(operations))
+	for i, operation := range operations {
+		names[i] = operation.Name
+	}
+	return names
+}
+
+// OperationNames parses a GraphQL document and returns the names of its
+// operations, so a missing operation name can be derived from the document body
+func OperationNames(input string) ([]string, error) {
+	document, err := NewParser(input).ParseDocument()
+	if err != nil {
+		return nil, err
+	}
+	return document.OperationNames(), nil
+}
+
+// operationKeyword returns the keyword an operation is written with, deriving it
+// from the node type for nodes built without an OperationType
+func (n *Node) operationKeyword() string {
+	if n.OperationType != "" {
+		return n.OperationType
+	}
+	return strings.ToLower(string(n.Type))
+}
+
+// ResponseKey returns the key a field appears under in the response: its alias if set, otherwise its name
+func (n *Node) ResponseKey() string {
+	if n.Alias != "" {
+		return n.Alias
+	}
+	return n.Name
 }
 
 // Print returns a string representation of the node with proper indentation
 func (n *Node) Print(indent string) string {
-	result := fmt.Sprintf("%s%s: %s\n", indent, n.Type, n.Name)
+	result := fmt.Sprintf("%s%s: ", indent, n.Type)
+	if n.Alias != "" {
+		result += n.Alias + ": "
+	}
+	result += n.Name
+	if n.TypeCondition != "" {
+		if n.Name != "" {
+			result += " "
+		}
+		result += "on " + n.TypeCondition
+	}
+	result += "\n"
 
-	for argName, argValue := range n.Arguments {
-		result += fmt.Sprintf("%s  Arg: %s = %s\n", indent, argName, argValue)
+	for _, definition := range n.VariableDefinitions {
+		result += fmt.Sprintf("%s  Var: %s\n", indent, definition)
+	}
+
+	for _, arg := range n.Arguments {
+		result += fmt.Sprintf("%s  Arg: %s = %s\n", indent, arg.Name, arg.Value.String())
 	}
 
 	for _, directive := range n.Directives {
 		result += fmt.Sprintf("%s  Directive: @%s\n", indent, directive.Name)
-		for argName, argValue := range directive.Arguments {
-			result += fmt.Sprintf("%s    Arg: %s = %s\n", indent, argName, argValue)
+		for _, arg := range directive.Arguments {
+			result += fmt.Sprintf("%s    Arg: %s = %s\n", indent, arg.Name, arg.Value.String())
 		}
 	}
 
@@ -51,49 +192,245 @@ func (n *Node) Print(indent string) string {
 
 // Parser represents a parser for GraphQL queries
 type Parser struct {
-	lexer *lexer.Lexer
-	curr  lexer.Token
+	input        string // Source being parsed, kept to quote it in errors
+	lexer        *lexer.Lexer
+	curr         lexer.Token
+	options      Options
+	tokens       int             // Number of tokens consumed so far
+	depth        int             // Current nesting of selection sets and composite values
+	comments     []string        // Comments directly preceding curr when KeepComments is set
+	next         lexer.Token     // Token after curr, when hasNext is set by peek
+	hasNext      bool            // Whether next holds a token read by peek
+	nextComments []string        // Comments skipped by peek, which precede next
+	ctx          context.Context // Checked at each field while parsing with ParseQueryContext
+}
+
+// Options configures limits applied while parsing
+type Options struct {
+	// MaxTokens caps the number of tokens the parser consumes before failing.
+	// Zero means unlimited.
+	MaxTokens int
+
+	// MaxDepth caps how deeply selection sets, lists, input objects, and list types may nest
+	// so hostile input cannot exhaust the stack. Zero means DefaultMaxDepth.
+	MaxDepth int
+
+	// KeepComments attaches # comments to the node that follows them as
+	// LeadingComments. By default comments are discarded.
+	KeepComments bool
 }
 
+// DefaultMaxDepth is the nesting limit used when Options.MaxDepth is zero
+const DefaultMaxDepth = 128
+
 // NewParser creates a new parser for the given input string
 func NewParser(input string) *Parser {
-	lex := lexer.NewLexer(input)
-	return &Parser{lexer: lex, curr: lex.NextToken()}
+	return NewParserWithOptions(input, Options{})
+}
+
+// NewParserWithOptions creates a new parser for the given input string using the given options
+func NewParserWithOptions(input string, options Options) *Parser {
+	p := &Parser{input: input, lexer: newLexer(input, options), options: options}
+	p.advance()
+	return p
+}
+
+// newLexer creates a lexer for input that emits comments only if the options keep them
+func newLexer(input string, options Options) *lexer.Lexer {
+	if options.KeepComments {
+		return lexer.NewLexerWithComments(input)
+	}
+	return lexer.NewLexer(input)
+}
+
+// Reset discards all parsing state and prepares the parser to parse input,
+// reusing its lexer so parsers can be pooled. Options are kept. Resetting to
+// an empty input drops every reference to the previous input, which pooled
+// parsers should do before they are returned to the pool.
+func (p *Parser) Reset(input string) {
+	l := p.lexer
+	if l == nil {
+		l = newLexer(input, p.options)
+	} else {
+		l.Reset(input)
+	}
+	*p = Parser{input: input, lexer: l, options: p.options}
+	p.advance()
+}
+
+// advance moves to the next token, collecting the comments directly before it
+func (p *Parser) advance() {
+	p.peek()
+	p.curr, p.comments = p.next, p.nextComments
+	p.hasNext, p.nextComments = false, nil
+}
+
+// peek returns the token after the current one without consuming it. Comments
+// in between are skipped and kept so advance can attach them to that token.
+func (p *Parser) peek() lexer.Token {
+	if !p.hasNext {
+		p.next = p.lexer.NextToken()
+		for p.next.Type == lexer.TokenComment {
+			p.nextComments = append(p.nextComments, p.next.Value)
+			p.next = p.lexer.NextToken()
+		}
+		p.hasNext = true
+	}
+	return p.next
+}
+
+// leadingComments returns the comments directly preceding the current token so
+// they can be attached to the node starting there
+func (p *Parser) leadingComments() []string {
+	comments := p.comments
+	p.comments = nil
+	return comments
+}
+
+// enter records one more level of nesting, failing once it exceeds the depth limit
+func (p *Parser) enter() {
+	limit := p.options.MaxDepth
+	if limit == 0 {
+		limit = DefaultMaxDepth
+	}
+	p.depth++
+	if p.depth > limit {
+		p.fail("Nesting depth limit of %d exceeded", limit)
+	}
+}
+
+// leave records the end of a level of nesting started by enter
+func (p *Parser) leave() {
+	p.depth--
 }
 
 // eat consumes the current token if it matches the expected type
 func (p *Parser) eat(t lexer.TokenType) {
-	if p.curr.Type == t {
-		p.curr = p.lexer.NextToken()
-	} else {
-		panic(fmt.Sprintf("Unexpected token: expected %s but got %s", t, p.curr.Type))
+	if p.curr.Type != t {
+		p.fail("Unexpected token: expected %s but got %s", t, p.curr.Type)
+	}
+	p.tokens++
+	if p.options.MaxTokens > 0 && p.tokens > p.options.MaxTokens {
+		p.fail("Token limit of %d exceeded", p.options.MaxTokens)
+	}
+	p.advance()
+}
+
+// expectKeyword consumes the current token if it is the identifier with the given value
+func (p *Parser) expectKeyword(keyword string) {
+	if p.curr.Type != lexer.TokenIdent || p.curr.Value != keyword {
+		p.fail("Unexpected token: expected %q but got %s %q", keyword, p.curr.Type, p.curr.Value)
+	}
+	p.eat(lexer.TokenIdent)
+}
+
+// parseDirectives parses any directives at the current position, tagging them with the given location
+func (p *Parser) parseDirectives(location DirectiveLocation) []*Node {
+	var directives []*Node
+	for p.curr.Type == lexer.TokenAt {
+		directive := p.ParseDirective()
+		directive.Location = location
+		directives = append(directives, directive)
+	}
+	return directives
+}
+
+// parseSelectionSet parses a braced list of fields, fragment spreads, and inline
+// fragments. An empty selection set {} yields no selections.
+func (p *Parser) parseSelectionSet() []*Node {
+	p.enter()
+	defer p.leave()
+	p.eat(lexer.TokenBraceL)
+	var selectionSet []*Node
+	for p.curr.Type == lexer.TokenIdent || p.curr.Type == lexer.TokenSpread {
+		if p.curr.Type == lexer.TokenSpread {
+			selectionSet = append(selectionSet, p.parseFragment())
+		} else {
+			selectionSet = append(selectionSet, p.ParseField())
+		}
+	}
+	if p.curr.Type != lexer.TokenBraceR && p.curr.Type != lexer.TokenIllegal && p.curr.Type != lexer.TokenEOF {
+		p.fail("Unexpected token in selection set: expected a field, fragment, or } but got %s %q", p.curr.Type, p.curr.Value)
+	}
+	p.eat(lexer.TokenBraceR)
+	return selectionSet
+}
+
+// parseFragment parses the selection following a spread token: either a named
+// fragment spread such as ...UserFields, or an inline fragment such as
+// ... on Admin @include(if: $admin) { permissions } whose type condition is optional
+func (p *Parser) parseFragment() *Node {
+	comments := p.leadingComments()
+	p.eat(lexer.TokenSpread)
+	if p.curr.Type == lexer.TokenIdent && p.curr.Value != "on" {
+		name := p.curr.Value
+		p.eat(lexer.TokenIdent)
+		return &Node{
+			Type:            NodeFragmentSpread,
+			Name:            name,
+			Directives:      p.parseDirectives(LocationFragmentSpread),
+			LeadingComments: comments,
+		}
+	}
+
+	var typeCondition string
+	if p.curr.Type == lexer.TokenIdent {
+		p.expectKeyword("on")
+		typeCondition = p.curr.Value
+		p.eat(lexer.TokenIdent)
+	}
+	directives := p.parseDirectives(LocationInlineFragment)
+
+	return &Node{
+		Type:            NodeInlineFragment,
+		TypeCondition:   typeCondition,
+		Directives:      directives,
+		SelectionSet:    p.parseSelectionSet(),
+		LeadingComments: comments,
+	}
+}
+
+// parseArguments parses an optional parenthesized argument list, keeping source order
+func (p *Parser) parseArguments() []Argument {
+	if p.curr.Type != lexer.TokenParenL {
+		return nil
 	}
+	p.eat(lexer.TokenParenL)
+	var args []Argument
+	// Parse name: value pairs until the closing parenthesis. At least one argument
+	// is required, and commas between them are skipped by the lexer.
+	for {
+		arg := p.parseArgument()
+		for _, existing := range args {
+			if existing.Name == arg.Name {
+				p.fail("Duplicate argument %q", arg.Name)
+			}
+		}
+		args = append(args, arg)
+		if p.curr.Type == lexer.TokenParenR {
+			break
+		}
+	}
+	p.eat(lexer.TokenParenR)
+	return args
+}
+
+// parseArgument parses a single name: value argument pair
+func (p *Parser) parseArgument() Argument {
+	name := p.curr.Value
+	p.eat(lexer.TokenIdent)
+	p.eat(lexer.TokenColon)
+	return Argument{Name: name, Value: p.parseValue()}
 }
 
-// ParseDirective parses a directive in a GraphQL query
+// ParseDirective parses a directive in a GraphQL query.
+// It panics with a *ParseError if the input is invalid.
 func (p *Parser) ParseDirective() *Node {
 	p.eat(lexer.TokenAt)
 	name := p.curr.Value
 	p.eat(lexer.TokenIdent)
 
-	args := make(map[string]string)
-	if p.curr.Type == lexer.TokenParenL {
-		p.eat(lexer.TokenParenL)
-		// Parse one or more arguments
-		for p.curr.Type == lexer.TokenIdent {
-			argName := p.curr.Value
-			p.eat(lexer.TokenIdent)
-			p.eat(lexer.TokenColon)
-			argValue := p.curr.Value
-			p.eat(lexer.TokenString)
-			// Strip quotes from string values
-			argValue = strings.Trim(argValue, "\"")
-			args[argName] = argValue
-			// If there are more arguments, they need to be separated properly
-			// In a more complete implementation, we would handle commas here
-		}
-		p.eat(lexer.TokenParenR)
-	}
+	args := p.parseArguments()
 
 	return &Node{
 		Type:      NodeDirective,
@@ -102,72 +439,171 @@ func (p *Parser) ParseDirective() *Node {
 	}
 }
 
-// ParseField parses a field in a GraphQL query
+// ParseField parses a field in a GraphQL query.
+// It panics with a *ParseError if the input is invalid.
 func (p *Parser) ParseField() *Node {
-	name := p.curr.Value
-	p.eat(lexer.TokenIdent)
+	p.checkContext()
+	comments := p.leadingComments()
 
-	args := make(map[string]string)
-	if p.curr.Type == lexer.TokenParenL {
-		p.eat(lexer.TokenParenL)
-		argName := p.curr.Value
+	// An identifier followed by a colon is an alias for the field name that follows
+	var alias string
+	if p.peek().Type == lexer.TokenColon {
+		alias = p.curr.Value
 		p.eat(lexer.TokenIdent)
 		p.eat(lexer.TokenColon)
-		argValue := p.curr.Value
-		p.eat(lexer.TokenString)
-		// Strip quotes from string values
-		argValue = strings.Trim(argValue, "\"")
-		args[argName] = argValue
-		p.eat(lexer.TokenParenR)
 	}
+	name := p.curr.Value
+	p.eat(lexer.TokenIdent)
 
-	// Parse directives if present
-	var directives []*Node
-	for p.curr.Type == lexer.TokenAt {
-		directives = append(directives, p.ParseDirective())
+	args := p.parseArguments()
+
+	// Parse directives if present; the grammar only allows them after the arguments
+	directives := p.parseDirectives(LocationField)
+	if len(directives) > 0 && p.curr.Type == lexer.TokenParenL {
+		p.fail("Arguments of field %q must come before its directives", name)
 	}
 
 	var selectionSet []*Node
 	if p.curr.Type == lexer.TokenBraceL {
-		p.eat(lexer.TokenBraceL)
-		for p.curr.Type == lexer.TokenIdent {
-			selectionSet = append(selectionSet, p.ParseField())
-		}
-		p.eat(lexer.TokenBraceR)
+		selectionSet = p.parseSelectionSet()
 	}
 
 	return &Node{
-		Type:         NodeField,
-		Name:         name,
-		Arguments:    args,
-		Directives:   directives,
-		SelectionSet: selectionSet,
+		Type:            NodeField,
+		Name:            name,
+		Alias:           alias,
+		Arguments:       args,
+		Directives:      directives,
+		SelectionSet:    selectionSet,
+		LeadingComments: comments,
 	}
 }
 
-// ParseQuery parses a GraphQL query
-func (p *Parser) ParseQuery() *Node {
-	p.eat(lexer.TokenIdent) // eat "query"
-	name := p.curr.Value
+// ParseDocument parses a complete GraphQL document containing one or more
+// operations and fragment definitions, returning a *ParseError if the input is invalid
+func (p *Parser) ParseDocument() (node *Node, err error) {
+	defer recoverError(&err)
+	return p.parseDocument(), nil
+}
+
+// parseDocument parses top-level definitions until the end of input
+func (p *Parser) parseDocument() *Node {
+	document := &Node{Type: NodeDocument}
+	for {
+		if p.curr.Type == lexer.TokenIdent && p.curr.Value == "fragment" {
+			document.SelectionSet = append(document.SelectionSet, p.parseFragmentDefinition())
+		} else {
+			document.SelectionSet = append(document.SelectionSet, p.parseQuery())
+		}
+		if p.curr.Type == lexer.TokenEOF {
+			return document
+		}
+	}
+}
+
+// ParseQuery parses a GraphQL operation (a query, mutation, or subscription),
+// returning a *ParseError if the input is invalid
+func (p *Parser) ParseQuery() (node *Node, err error) {
+	defer recoverError(&err)
+	return p.parseQuery(), nil
+}
+
+// ParseQueryContext is like ParseQuery, but checks ctx at each field and stops
+// with the context's error once it is cancelled or its deadline has passed
+func (p *Parser) ParseQueryContext(ctx context.Context) (node *Node, err error) {
+	defer recoverError(&err)
+	p.ctx = ctx
+	defer func() { p.ctx = nil }()
+	return p.parseQuery(), nil
+}
+
+// checkContext stops parsing with the context error if the context passed to
+// ParseQueryContext is done
+func (p *Parser) checkContext() {
+	if p.ctx == nil {
+		return
+	}
+	if err := p.ctx.Err(); err != nil {
+		panic(contextError{err})
+	}
+}
+
+// parseQuery parses a GraphQL operation
+func (p *Parser) parseQuery() *Node {
+	comments := p.leadingComments()
+
+	// The shorthand { ... } form is an anonymous query
+	if p.curr.Type == lexer.TokenBraceL {
+		return &Node{Type: NodeQuery, OperationType: "query", SelectionSet: p.parseSelectionSet(), LeadingComments: comments}
+	}
+
+	operationType := p.curr.Value
+	kind, ok := operationKinds[operationType]
+	if p.curr.Type == lexer.TokenIdent && typeSystemKeywords[operationType] {
+		p.fail("Type system definitions are not supported: got %q, but only operations and fragments can be parsed", operationType)
+	}
+	if p.curr.Type != lexer.TokenIdent || !ok {
+		p.fail("Unexpected token: expected query, mutation, or subscription but got %s %q", p.curr.Type, p.curr.Value)
+	}
 	p.eat(lexer.TokenIdent)
 
-	// Parse directives at query level if present
-	var directives []*Node
-	for p.curr.Type == lexer.TokenAt {
-		directives = append(directives, p.ParseDirective())
+	// The operation name is optional
+	var name string
+	if p.curr.Type == lexer.TokenIdent {
+		name = p.curr.Value
+		p.eat(lexer.TokenIdent)
 	}
 
-	p.eat(lexer.TokenBraceL)
-	var selectionSet []*Node
-	for p.curr.Type == lexer.TokenIdent {
-		selectionSet = append(selectionSet, p.ParseField())
+	variableDefinitions := p.parseVariableDefinitions()
+
+	// Parse directives at operation level if present; they follow the variable definitions
+	directives := p.parseDirectives(kind.location)
+	if len(directives) > 0 && p.curr.Type == lexer.TokenParenL {
+		p.fail("Variable definitions must come before the directives of the %s", operationType)
 	}
-	p.eat(lexer.TokenBraceR)
+	selectionSet := p.parseSelectionSet()
+
+	return &Node{
+		Type:                kind.nodeType,
+		OperationType:       operationType,
+		Name:                name,
+		VariableDefinitions: variableDefinitions,
+		Directives:          directives,
+		SelectionSet:        selectionSet,
+		LeadingComments:     comments,
+	}
+}
+
+// ParseFragmentDefinition parses a fragment definition of the form
+// fragment Name on Type @directive { ... }, returning a *ParseError if the input is invalid
+func (p *Parser) ParseFragmentDefinition() (node *Node, err error) {
+	defer recoverError(&err)
+	return p.parseFragmentDefinition(), nil
+}
+
+// parseFragmentDefinition parses a fragment definition
+func (p *Parser) parseFragmentDefinition() *Node {
+	comments := p.leadingComments()
+	p.expectKeyword("fragment")
+	name := p.curr.Value
+	if p.curr.Type == lexer.TokenIdent && name == "on" {
+		// A spread of a fragment named on would be read as an inline fragment
+		p.fail("Fragment name cannot be %q", name)
+	}
+	p.eat(lexer.TokenIdent)
+	p.expectKeyword("on")
+	typeCondition := p.curr.Value
+	p.eat(lexer.TokenIdent)
+
+	directives := p.parseDirectives(LocationFragmentDefinition)
+	selectionSet := p.parseSelectionSet()
 
 	return &Node{
-		Type:         NodeQuery,
-		Name:         name,
-		Directives:   directives,
-		SelectionSet: selectionSet,
+		Type:            NodeFragmentDefinition,
+		Name:            name,
+		TypeCondition:   typeCondition,
+		Directives:      directives,
+		SelectionSet:    selectionSet,
+		LeadingComments: comments,
 	}
 }
@@ -2,8 +2,12 @@
 package parser
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/tom/graphqlinsights/pkg/lexer"
@@ -26,7 +30,7 @@ func TestParseQuery(t *testing.T) {
 					{
 						Type:      NodeField,
 						Name:      "user",
-						Arguments: map[string]string{"id": "123"},
+						Arguments: []Argument{{Name: "id", Value: &StringValue{Value: "123"}}},
 						SelectionSet: []*Node{
 							{Type: NodeField, Name: "name"},
 						},
@@ -44,7 +48,7 @@ func TestParseQuery(t *testing.T) {
 					{
 						Type:      NodeField,
 						Name:      "user",
-						Arguments: map[string]string{"id": "123"},
+						Arguments: []Argument{{Name: "id", Value: &StringValue{Value: "123"}}},
 						SelectionSet: []*Node{
 							{Type: NodeField, Name: "name"},
 							{
@@ -69,7 +73,7 @@ func TestParseQuery(t *testing.T) {
 					{
 						Type:      NodeField,
 						Name:      "user",
-						Arguments: map[string]string{"id": "123"},
+						Arguments: []Argument{{Name: "id", Value: &StringValue{Value: "123"}}},
 						Directives: []*Node{
 							{
 								Type: NodeDirective,
@@ -93,12 +97,12 @@ func TestParseQuery(t *testing.T) {
 					{
 						Type:      NodeField,
 						Name:      "user",
-						Arguments: map[string]string{"id": "123"},
+						Arguments: []Argument{{Name: "id", Value: &StringValue{Value: "123"}}},
 						Directives: []*Node{
 							{
 								Type:      NodeDirective,
 								Name:      "cache",
-								Arguments: map[string]string{"ttl": "300"},
+								Arguments: []Argument{{Name: "ttl", Value: &StringValue{Value: "300"}}},
 							},
 						},
 						SelectionSet: []*Node{
@@ -124,7 +128,7 @@ func TestParseQuery(t *testing.T) {
 					{
 						Type:      NodeField,
 						Name:      "user",
-						Arguments: map[string]string{"id": "123"},
+						Arguments: []Argument{{Name: "id", Value: &StringValue{Value: "123"}}},
 						SelectionSet: []*Node{
 							{Type: NodeField, Name: "name"},
 						},
@@ -152,12 +156,12 @@ func TestParseQuery(t *testing.T) {
 					{
 						Type:      NodeField,
 						Name:      "user",
-						Arguments: map[string]string{"id": "123"},
+						Arguments: []Argument{{Name: "id", Value: &StringValue{Value: "123"}}},
 						Directives: []*Node{
 							{
 								Type:      NodeDirective,
 								Name:      "cache",
-								Arguments: map[string]string{"ttl": "300"},
+								Arguments: []Argument{{Name: "ttl", Value: &StringValue{Value: "300"}}},
 							},
 						},
 						SelectionSet: []*Node{
@@ -174,7 +178,10 @@ func TestParseQuery(t *testing.T) {
 			log.Printf("Running test: %s", tt.name)
 			lex := lexer.NewLexer(tt.input)
 			parser := &Parser{lexer: lex, curr: lex.NextToken()}
-			parsedQuery := parser.ParseQuery()
+			parsedQuery, err := parser.ParseQuery()
+			if err != nil {
+				t.Fatalf("Unexpected parse error: %s", err)
+			}
 
 			// Use our custom compareNodes function to compare node structures
 			if !compareNodes(parsedQuery, tt.want) {
@@ -185,6 +192,16 @@ func TestParseQuery(t *testing.T) {
 	}
 }
 
+// mustParseQuery parses input as a query, failing the test on a parse error
+func mustParseQuery(t *testing.T, input string) *Node {
+	t.Helper()
+	query, err := NewParser(input).ParseQuery()
+	if err != nil {
+		t.Fatalf("Unexpected parse error for %q: %s", input, err)
+	}
+	return query
+}
+
 // Helper function for detailed comparison and debugging
 func detailedCompare(got, want *Node) string {
 	if got == nil && want == nil {
@@ -206,21 +223,27 @@ func detailedCompare(got, want *Node) string {
 	if got.Name != want.Name {
 		result += fmt.Sprintf("Name mismatch: got %s, want %s\n", got.Name, want.Name)
 	}
+	if got.Alias != want.Alias {
+		result += fmt.Sprintf("Alias mismatch: got %s, want %s\n", got.Alias, want.Alias)
+	}
+	if got.TypeCondition != want.TypeCondition {
+		result += fmt.Sprintf("TypeCondition mismatch: got %s, want %s\n", got.TypeCondition, want.TypeCondition)
+	}
+
+	if !variableDefinitionsEqual(got.VariableDefinitions, want.VariableDefinitions) {
+		result += fmt.Sprintf("VariableDefinitions mismatch: got %v, want %v\n", got.VariableDefinitions, want.VariableDefinitions)
+	}
 
 	// Compare arguments
 	if len(got.Arguments) != len(want.Arguments) {
 		result += fmt.Sprintf("Arguments length mismatch: got %d, want %d\n", len(got.Arguments), len(want.Arguments))
 	} else {
-		for k, v := range got.Arguments {
-			if wantVal, ok := want.Arguments[k]; !ok {
-				result += fmt.Sprintf("Missing argument in want: %s\n", k)
-			} else if wantVal != v {
-				result += fmt.Sprintf("Argument value mismatch for %s: got %s, want %s\n", k, v, wantVal)
-			}
-		}
-		for k := range want.Arguments {
-			if _, ok := got.Arguments[k]; !ok {
-				result += fmt.Sprintf("Missing argument in got: %s\n", k)
+		for i, arg := range got.Arguments {
+			wantArg := want.Arguments[i]
+			if arg.Name != wantArg.Name {
+				result += fmt.Sprintf("Argument[%d] name mismatch: got %s, want %s\n", i, arg.Name, wantArg.Name)
+			} else if !valuesEqual(wantArg.Value, arg.Value) {
+				result += fmt.Sprintf("Argument value mismatch for %s: got %s, want %s\n", arg.Name, arg.Value, wantArg.Value)
 			}
 		}
 	}
@@ -266,7 +289,11 @@ func compareNodes(got, want *Node) bool {
 	}
 
 	// Compare basic properties
-	if got.Type != want.Type || got.Name != want.Name {
+	if got.Type != want.Type || got.Name != want.Name || got.Alias != want.Alias || got.TypeCondition != want.TypeCondition {
+		return false
+	}
+
+	if !variableDefinitionsEqual(got.VariableDefinitions, want.VariableDefinitions) {
 		return false
 	}
 
@@ -274,9 +301,8 @@ func compareNodes(got, want *Node) bool {
 	if len(got.Arguments) != len(want.Arguments) {
 		return false
 	}
-	for k, v := range got.Arguments {
-		wantVal, ok := want.Arguments[k]
-		if !ok || wantVal != v {
+	for i, arg := range got.Arguments {
+		if arg.Name != want.Arguments[i].Name || !valuesEqual(want.Arguments[i].Value, arg.Value) {
 			return false
 		}
 	}
@@ -302,3 +328,1395 @@ func compareNodes(got, want *Node) bool {
 	}
 	return true
 }
+
+func TestParseErrorPosition(t *testing.T) {
+	log.Println("Starting TestParseErrorPosition")
+	input := "query GetUser {\n  user(id: \"123\") {\n    name\n  }\n  )\n}"
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "LF line endings", input: input},
+		{name: "CRLF line endings", input: strings.ReplaceAll(input, "\n", "\r\n")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewParser(tt.input).ParseQuery()
+			if err == nil {
+				t.Fatalf("Expected ParseQuery to fail on invalid input")
+			}
+			parseErr, ok := err.(*ParseError)
+			if !ok {
+				t.Fatalf("Expected a *ParseError, got %T", err)
+			}
+			if parseErr.Line != 5 || parseErr.Column != 3 {
+				t.Errorf("Expected error at line 5, column 3, got line %d, column %d", parseErr.Line, parseErr.Column)
+			}
+			if !strings.Contains(err.Error(), "line 5, column 3") {
+				t.Errorf("Expected error message to report line 5, column 3, got %q", err.Error())
+			}
+		})
+	}
+}
+
+func TestParseErrorSnippet(t *testing.T) {
+	log.Println("Starting TestParseErrorSnippet")
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "Short input",
+			input: `query X { a ) }`,
+			want:  `query X { a ) }`,
+		},
+		{
+			name:  "Long input is trimmed around the token",
+			input: `query GetUserProfile { user(id: "1") { name ) email avatarUrl createdAt } }`,
+			want:  `ser(id: "1") { name ) email avatarUrl cr`,
+		},
+		{
+			name:  "Line breaks become spaces",
+			input: "query X {\n  a\n  )\n}",
+			want:  "query X {   a   ) }",
+		},
+		{
+			name:  "Multi-byte characters are kept whole",
+			input: `{ a(s: "ééééééééééé") ) }`,
+			want:  `éééééééé") ) }`,
+		},
+		{
+			name:  "End of input",
+			input: `query X { a`,
+			want:  `query X { a`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewParser(tt.input).ParseQuery()
+			parseErr, ok := err.(*ParseError)
+			if !ok {
+				t.Fatalf("Expected a *ParseError, got %T: %v", err, err)
+			}
+			if parseErr.Snippet != tt.want {
+				t.Errorf("Expected snippet %q, got %q", tt.want, parseErr.Snippet)
+			}
+		})
+	}
+}
+
+func TestParseFragmentDefinition(t *testing.T) {
+	log.Println("Starting TestParseFragmentDefinition")
+	input := `fragment UserFields on User @include(if: "true") { id name }`
+	want := &Node{
+		Type:          NodeFragmentDefinition,
+		Name:          "UserFields",
+		TypeCondition: "User",
+		Directives: []*Node{
+			{
+				Type:      NodeDirective,
+				Name:      "include",
+				Arguments: []Argument{{Name: "if", Value: &StringValue{Value: "true"}}},
+			},
+		},
+		SelectionSet: []*Node{
+			{Type: NodeField, Name: "id"},
+			{Type: NodeField, Name: "name"},
+		},
+	}
+
+	got, err := NewParser(input).ParseFragmentDefinition()
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+	if !compareNodes(got, want) {
+		t.Fatalf("Node structures not equal: %s", detailedCompare(got, want))
+	}
+	if got.TypeCondition != want.TypeCondition {
+		t.Errorf("Expected type condition %s, got %s", want.TypeCondition, got.TypeCondition)
+	}
+	if got.Directives[0].Location != LocationFragmentDefinition {
+		t.Errorf("Expected directive location %s, got %s", LocationFragmentDefinition, got.Directives[0].Location)
+	}
+}
+
+func TestDirectiveLocations(t *testing.T) {
+	log.Println("Starting TestDirectiveLocations")
+	query := mustParseQuery(t, `query GetUser @persist { user @cache { name } }`)
+
+	if loc := query.Directives[0].Location; loc != LocationQuery {
+		t.Errorf("Expected query directive location %s, got %s", LocationQuery, loc)
+	}
+	if loc := query.SelectionSet[0].Directives[0].Location; loc != LocationField {
+		t.Errorf("Expected field directive location %s, got %s", LocationField, loc)
+	}
+}
+
+func TestDirectivePlacement(t *testing.T) {
+	log.Println("Starting TestDirectivePlacement")
+	input := `
+		query Feed($first: Int = 10 @a1 @a2, $after: String) @q1(v: 1) @q2 @q3 {
+			posts(first: $first, after: $after) @f1 @f2(if: true) { title }
+			viewer @f3 { name(format: SHORT) }
+			...Shared @s1 @s2
+			... on Video @i1 @i2 { duration }
+		}
+		fragment Shared on Post @d1 @d2(x: [1, 2]) { id }
+	`
+	document, err := NewParser(input).ParseDocument()
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+	query, fragment := document.SelectionSet[0], document.SelectionSet[1]
+	posts, viewer := query.SelectionSet[0], query.SelectionSet[1]
+
+	directiveNames := func(directives []*Node) []string {
+		var names []string
+		for _, directive := range directives {
+			names = append(names, directive.Name)
+		}
+		return names
+	}
+	tests := []struct {
+		name       string
+		directives []*Node
+		want       []string
+	}{
+		{name: "Variable definition", directives: query.VariableDefinitions[0].Directives, want: []string{"a1", "a2"}},
+		{name: "Operation after variables", directives: query.Directives, want: []string{"q1", "q2", "q3"}},
+		{name: "Field after arguments", directives: posts.Directives, want: []string{"f1", "f2"}},
+		{name: "Field without arguments", directives: viewer.Directives, want: []string{"f3"}},
+		{name: "Fragment spread", directives: query.SelectionSet[2].Directives, want: []string{"s1", "s2"}},
+		{name: "Inline fragment", directives: query.SelectionSet[3].Directives, want: []string{"i1", "i2"}},
+		{name: "Fragment definition", directives: fragment.Directives, want: []string{"d1", "d2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := directiveNames(tt.directives); !slices.Equal(got, tt.want) {
+				t.Errorf("Expected directives %v, got %v", tt.want, got)
+			}
+		})
+	}
+	if len(posts.Arguments) != 2 || posts.Argument("after") == nil {
+		t.Errorf("Expected both arguments of posts to be parsed, got %v", posts.Arguments)
+	}
+
+	errorTests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "Field arguments after directives",
+			input: `{ posts @cache(ttl: 60) (first: 10) { title } }`,
+			want:  `Arguments of field "posts" must come before its directives at line 1, column 25`,
+		},
+		{
+			name:  "Variable definitions after directives",
+			input: `query Feed @live(v: 1) ($first: Int) { posts }`,
+			want:  `Variable definitions must come before the directives of the query at line 1, column 24`,
+		},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewParser(tt.input).ParseQuery()
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Expected error %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestKeepComments(t *testing.T) {
+	log.Println("Starting TestKeepComments")
+	input := `# Loads the feed
+# for the home page
+query Feed {
+  # Who is asking
+  viewer { id }
+  posts(
+    # not attached to anything
+    first: 10
+  ) {
+    title # trailing comments belong to the next node
+    #tagged
+    ...PostFields
+  }
+  # dangling before the closing brace
+}
+# Shared post fields
+fragment PostFields on Post { body }`
+
+	document, err := NewParserWithOptions(input, Options{KeepComments: true}).ParseDocument()
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+	query, fragment := document.SelectionSet[0], document.SelectionSet[1]
+	viewer, posts := query.SelectionSet[0], query.SelectionSet[1]
+	tests := []struct {
+		name string
+		node *Node
+		want []string
+	}{
+		{name: "Operation", node: query, want: []string{" Loads the feed", " for the home page"}},
+		{name: "Field", node: viewer, want: []string{" Who is asking"}},
+		{name: "Field after a nested selection", node: viewer.SelectionSet[0], want: nil},
+		{name: "Comment inside arguments", node: posts, want: nil},
+		{name: "Child of field with commented arguments", node: posts.SelectionSet[0], want: nil},
+		{name: "Fragment spread", node: posts.SelectionSet[1], want: []string{" trailing comments belong to the next node", "tagged"}},
+		{name: "Fragment definition", node: fragment, want: []string{" Shared post fields"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !slices.Equal(tt.node.LeadingComments, tt.want) {
+				t.Errorf("Expected comments %q, got %q", tt.want, tt.node.LeadingComments)
+			}
+		})
+	}
+
+	t.Run("Discarded by default", func(t *testing.T) {
+		document, err := NewParser(input).ParseDocument()
+		if err != nil {
+			t.Fatalf("Unexpected parse error: %s", err)
+		}
+		Walk(document, func(n *Node) bool {
+			if n.LeadingComments != nil {
+				t.Errorf("Expected no comments on %s %s, got %q", n.Type, n.Name, n.LeadingComments)
+			}
+			return true
+		})
+	})
+
+	t.Run("Kept after Reset", func(t *testing.T) {
+		p := NewParserWithOptions(`{ a }`, Options{KeepComments: true})
+		p.Reset("{\n  # note\n  a\n}")
+		query, err := p.ParseQuery()
+		if err != nil {
+			t.Fatalf("Unexpected parse error: %s", err)
+		}
+		if got := query.SelectionSet[0].LeadingComments; !slices.Equal(got, []string{" note"}) {
+			t.Errorf("Expected comments [\" note\"], got %q", got)
+		}
+	})
+
+	t.Run("Between an alias and its colon", func(t *testing.T) {
+		query, err := NewParserWithOptions("{ a # c\n : b\n # d\n e }", Options{KeepComments: true}).ParseQuery()
+		if err != nil {
+			t.Fatalf("Unexpected parse error: %s", err)
+		}
+		if field := query.SelectionSet[0]; field.Alias != "a" || field.Name != "b" {
+			t.Errorf("Expected a: b, got %s: %s", field.Alias, field.Name)
+		}
+		if got := query.SelectionSet[1].LeadingComments; !slices.Equal(got, []string{" d"}) {
+			t.Errorf("Expected comments [\" d\"], got %q", got)
+		}
+	})
+}
+
+func TestParseBlockStringArgument(t *testing.T) {
+	log.Println("Starting TestParseBlockStringArgument")
+	input := "query Q { search(text: \"\"\"\n    say \"hi\"\n  \"\"\") { id } }"
+	query := mustParseQuery(t, input)
+
+	if got := query.SelectionSet[0].Argument("text").(*StringValue).Value; got != `say "hi"` {
+		t.Errorf("Expected block string argument %q, got %q", `say "hi"`, got)
+	}
+}
+
+func TestParseUnterminatedString(t *testing.T) {
+	log.Println("Starting TestParseUnterminatedString")
+	_, err := NewParser(`query X { a(b: "unterminated) }`).ParseQuery()
+	if err == nil {
+		t.Fatalf("Expected ParseQuery to fail on an unterminated string")
+	}
+	if !strings.Contains(err.Error(), "unterminated string") {
+		t.Errorf("Expected error to mention the unterminated string, got %q", err.Error())
+	}
+}
+
+func TestParseDirectiveObjectValue(t *testing.T) {
+	log.Println("Starting TestParseDirectiveObjectValue")
+	input := `query GetDoc { document(filter: { owner: $uid }) @auth(rules: { role: ADMIN, ownerId: $uid }) { title } }`
+	rules := &ObjectValue{
+		Fields: []*ObjectField{
+			{Name: "role", Value: &EnumValue{Value: "ADMIN"}},
+			{Name: "ownerId", Value: &VariableValue{Name: "uid"}},
+		},
+	}
+	filter := &ObjectValue{
+		Fields: []*ObjectField{
+			{Name: "owner", Value: &VariableValue{Name: "uid"}},
+		},
+	}
+
+	field := mustParseQuery(t, input).SelectionSet[0]
+	if got := field.Directives[0].Argument("rules"); !valuesEqual(got, rules) {
+		t.Errorf("Expected directive argument %s, got %s", rules, got)
+	}
+	if got := field.Argument("filter"); !valuesEqual(got, filter) {
+		t.Errorf("Expected field argument %s, got %s", filter, got)
+	}
+	if got := field.Directives[0].Argument("rules").String(); got != "{role: ADMIN, ownerId: $uid}" {
+		t.Errorf("Unexpected serialized directive argument %s", got)
+	}
+}
+
+func TestMaxTokens(t *testing.T) {
+	log.Println("Starting TestMaxTokens")
+	input := `query GetUser { user(id: "123") { name email } }`
+	tests := []struct {
+		name      string
+		maxTokens int
+		wantErr   bool
+	}{
+		{name: "Unlimited by default", maxTokens: 0},
+		{name: "Within budget", maxTokens: 14},
+		{name: "Exceeds budget", maxTokens: 13, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewParserWithOptions(input, Options{MaxTokens: tt.maxTokens}).ParseQuery()
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "Token limit of 13 exceeded") {
+					t.Errorf("Expected token limit error, got %v", err)
+				}
+			} else if err != nil {
+				t.Errorf("Unexpected parse error: %s", err)
+			}
+		})
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	log.Println("Starting TestMaxDepth")
+	tests := []struct {
+		name     string
+		input    string
+		maxDepth int
+		wantErr  string
+	}{
+		{name: "Within default limit", input: nestedQuery(DefaultMaxDepth - 1)},
+		{name: "Beyond default limit", input: nestedQuery(100000), wantErr: "Nesting depth limit of 128 exceeded"},
+		{name: "Within custom limit", input: nestedQuery(4), maxDepth: 5},
+		{name: "Beyond custom limit", input: nestedQuery(5), maxDepth: 5, wantErr: "Nesting depth limit of 5 exceeded at line 1, column 32"},
+		{name: "Nested list values", input: `{ a(x: ` + strings.Repeat("[", 100000) + `) }`, wantErr: "Nesting depth limit of 128 exceeded"},
+		{name: "Nested object values", input: `{ a(x: ` + strings.Repeat("{ y: ", 200) + `) }`, wantErr: "Nesting depth limit of 128 exceeded"},
+		{name: "Nested list types", input: `query Q($v: ` + strings.Repeat("[", 1000000) + `Int` + strings.Repeat("]", 1000000) + `) { a }`, wantErr: "Nesting depth limit of 128 exceeded"},
+		{name: "List types within custom limit", input: `query Q($v: [[Int!]]) { a }`, maxDepth: 2},
+		{name: "List types beyond custom limit", input: `query Q($v: [[[Int]]]) { a }`, maxDepth: 2, wantErr: "Nesting depth limit of 2 exceeded at line 1, column 15"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewParserWithOptions(tt.input, Options{MaxDepth: tt.maxDepth}).ParseQuery()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected parse error: %s", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// cancelAfterContext is a context whose Err starts returning context.Canceled
+// after it has been checked a fixed number of times
+type cancelAfterContext struct {
+	context.Context
+	remaining int
+	checks    int
+}
+
+func (c *cancelAfterContext) Err() error {
+	c.checks++
+	if c.checks > c.remaining {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestParseQueryContext(t *testing.T) {
+	log.Println("Starting TestParseQueryContext")
+	input := "{ " + strings.Repeat("field ", 10000) + "}"
+
+	t.Run("Not cancelled", func(t *testing.T) {
+		query, err := NewParser(input).ParseQueryContext(context.Background())
+		if err != nil {
+			t.Fatalf("Unexpected parse error: %s", err)
+		}
+		if len(query.SelectionSet) != 10000 {
+			t.Errorf("Expected 10000 fields, got %d", len(query.SelectionSet))
+		}
+	})
+
+	t.Run("Cancelled mid-parse", func(t *testing.T) {
+		ctx := &cancelAfterContext{Context: context.Background(), remaining: 100}
+		query, err := NewParser(input).ParseQueryContext(ctx)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected %v, got %v", context.Canceled, err)
+		}
+		if query != nil {
+			t.Errorf("Expected no query, got %v", query)
+		}
+		if ctx.checks != 101 {
+			t.Errorf("Expected parsing to stop at the first check after cancellation, got %d checks", ctx.checks)
+		}
+	})
+
+	t.Run("Already cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := NewParser(input).ParseQueryContext(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected %v, got %v", context.Canceled, err)
+		}
+	})
+
+	t.Run("Context not kept after parsing", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		p := NewParser(`{ a }`)
+		if _, err := p.ParseQueryContext(ctx); err != nil {
+			t.Fatalf("Unexpected parse error: %s", err)
+		}
+		cancel()
+		p.Reset(`{ b }`)
+		if _, err := p.ParseQuery(); err != nil {
+			t.Errorf("Expected ParseQuery to ignore the earlier context, got %v", err)
+		}
+	})
+}
+
+func TestParseOperationTypes(t *testing.T) {
+	log.Println("Starting TestParseOperationTypes")
+	tests := []struct {
+		name     string
+		input    string
+		want     *Node
+		location DirectiveLocation
+	}{
+		{
+			name:  "Mutation",
+			input: `mutation CreateUser @trace { createUser(name: "Ada") { id } }`,
+			want: &Node{
+				Type:       NodeMutation,
+				Name:       "CreateUser",
+				Directives: []*Node{{Type: NodeDirective, Name: "trace"}},
+				SelectionSet: []*Node{
+					{
+						Type:         NodeField,
+						Name:         "createUser",
+						Arguments:    []Argument{{Name: "name", Value: &StringValue{Value: "Ada"}}},
+						SelectionSet: []*Node{{Type: NodeField, Name: "id"}},
+					},
+				},
+			},
+			location: LocationMutation,
+		},
+		{
+			name:  "Subscription",
+			input: `subscription OnMessage @trace { messageAdded { id text } }`,
+			want: &Node{
+				Type:       NodeSubscription,
+				Name:       "OnMessage",
+				Directives: []*Node{{Type: NodeDirective, Name: "trace"}},
+				SelectionSet: []*Node{
+					{
+						Type: NodeField,
+						Name: "messageAdded",
+						SelectionSet: []*Node{
+							{Type: NodeField, Name: "id"},
+							{Type: NodeField, Name: "text"},
+						},
+					},
+				},
+			},
+			location: LocationSubscription,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mustParseQuery(t, tt.input)
+			if !compareNodes(got, tt.want) {
+				t.Fatalf("Node structures not equal: %s", detailedCompare(got, tt.want))
+			}
+			if !got.IsOperation() {
+				t.Errorf("Expected %s node to be an operation", got.Type)
+			}
+			if loc := got.Directives[0].Location; loc != tt.location {
+				t.Errorf("Expected directive location %s, got %s", tt.location, loc)
+			}
+		})
+	}
+}
+
+func TestParseUnknownOperationType(t *testing.T) {
+	log.Println("Starting TestParseUnknownOperationType")
+	_, err := NewParser(`update User { id }`).ParseQuery()
+	if err == nil || !strings.Contains(err.Error(), "expected query, mutation, or subscription") {
+		t.Errorf("Expected an operation type error, got %v", err)
+	}
+}
+
+func TestParseTypeSystemDefinition(t *testing.T) {
+	log.Println("Starting TestParseTypeSystemDefinition")
+	tests := []struct {
+		name    string
+		input   string
+		keyword string
+	}{
+		{name: "Object type", input: `type User { id: ID }`, keyword: "type"},
+		{name: "Input type", input: `input UserFilter { name: String }`, keyword: "input"},
+		{name: "Enum", input: `enum Role { ADMIN USER }`, keyword: "enum"},
+		{name: "Scalar", input: `scalar DateTime`, keyword: "scalar"},
+		{name: "Interface", input: `interface Node { id: ID! }`, keyword: "interface"},
+		{name: "Union", input: `union SearchResult = User | Post`, keyword: "union"},
+		{name: "Schema", input: `schema { query: Query }`, keyword: "schema"},
+		{name: "Directive", input: `directive @cache(ttl: Int) on FIELD`, keyword: "directive"},
+		{name: "Extension", input: `extend type User { email: String }`, keyword: "extend"},
+		{name: "After an operation", input: `query Q { a } type User { id: ID }`, keyword: "type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewParser(tt.input).ParseDocument()
+			want := fmt.Sprintf("Type system definitions are not supported: got %q", tt.keyword)
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected error containing %s, got %v", want, err)
+			}
+		})
+	}
+
+	// Type system keywords remain valid field and operation names
+	if _, err := NewParser(`query type { type input enum }`).ParseDocument(); err != nil {
+		t.Errorf("Unexpected parse error: %s", err)
+	}
+}
+
+func TestParseAnonymousQuery(t *testing.T) {
+	log.Println("Starting TestParseAnonymousQuery")
+	want := &Node{
+		Type: NodeQuery,
+		SelectionSet: []*Node{
+			{
+				Type:         NodeField,
+				Name:         "me",
+				SelectionSet: []*Node{{Type: NodeField, Name: "id"}},
+			},
+		},
+	}
+
+	for _, input := range []string{`{ me { id } }`, `query { me { id } }`} {
+		t.Run(input, func(t *testing.T) {
+			got := mustParseQuery(t, input)
+			if !compareNodes(got, want) {
+				t.Errorf("Node structures not equal: %s", detailedCompare(got, want))
+			}
+		})
+	}
+}
+
+func TestParseMultipleFieldArguments(t *testing.T) {
+	log.Println("Starting TestParseMultipleFieldArguments")
+	input := `query Search { users(role: "admin", team: "core" status: ACTIVE) { name } }`
+	want := []Argument{
+		{Name: "role", Value: &StringValue{Value: "admin"}},
+		{Name: "team", Value: &StringValue{Value: "core"}},
+		{Name: "status", Value: &EnumValue{Value: "ACTIVE"}},
+	}
+
+	got := mustParseQuery(t, input).SelectionSet[0].Arguments
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d arguments, got %d", len(want), len(got))
+	}
+	for i, arg := range want {
+		if got[i].Name != arg.Name || !valuesEqual(got[i].Value, arg.Value) {
+			t.Errorf("Argument[%d]: expected %s: %s, got %s: %s", i, arg.Name, arg.Value, got[i].Name, got[i].Value)
+		}
+	}
+}
+
+func TestParseArgumentValueKinds(t *testing.T) {
+	log.Println("Starting TestParseArgumentValueKinds")
+	input := `query Q { items(s: "x", i: 10, f: 3.5, b: true, n: null, e: DESC, v: $after) { id } }`
+	want := []Argument{
+		{Name: "s", Value: &StringValue{Value: "x"}},
+		{Name: "i", Value: &IntValue{Raw: "10"}},
+		{Name: "f", Value: &FloatValue{Raw: "3.5"}},
+		{Name: "b", Value: &BoolValue{Value: true}},
+		{Name: "n", Value: &NullValue{}},
+		{Name: "e", Value: &EnumValue{Value: "DESC"}},
+		{Name: "v", Value: &VariableValue{Name: "after"}},
+	}
+
+	got := mustParseQuery(t, input).SelectionSet[0].Arguments
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d arguments, got %d", len(want), len(got))
+	}
+	for i, arg := range want {
+		if got[i].Name != arg.Name || !valuesEqual(got[i].Value, arg.Value) {
+			t.Errorf("Argument[%d]: expected %s %s: %s, got %s: %v", i, arg.Value.Kind(), arg.Name, arg.Value, got[i].Name, got[i].Value)
+		}
+	}
+}
+
+func TestParseEnumAndBooleanArguments(t *testing.T) {
+	log.Println("Starting TestParseEnumAndBooleanArguments")
+	tests := []struct {
+		name     string
+		input    string
+		wantKind ValueKind
+		wantText string
+	}{
+		{name: "Enum", input: `{ users(status: ACTIVE) { id } }`, wantKind: ValueEnum, wantText: "ACTIVE"},
+		{name: "Boolean", input: `{ users(flag: true) { id } }`, wantKind: ValueBoolean, wantText: "true"},
+		{name: "Null", input: `{ users(flag: null) { id } }`, wantKind: ValueNull, wantText: "null"},
+		{name: "Enum that looks like a keyword", input: `{ users(status: True) { id } }`, wantKind: ValueEnum, wantText: "True"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := mustParseQuery(t, tt.input)
+			arg := query.SelectionSet[0].Arguments[0]
+			if arg.Value.Kind() != tt.wantKind || arg.Value.String() != tt.wantText {
+				t.Errorf("Expected %s %s, got %s %s", tt.wantKind, tt.wantText, arg.Value.Kind(), arg.Value)
+			}
+			// Enum, boolean, and null values serialize without quotes
+			if got := query.String(); got != tt.input {
+				t.Errorf("Expected %s, got %s", tt.input, got)
+			}
+		})
+	}
+}
+
+func TestParseNullArgument(t *testing.T) {
+	log.Println("Starting TestParseNullArgument")
+	tests := []struct {
+		name      string
+		input     string
+		field     string
+		wantValue Value
+	}{
+		{
+			name:      "Null keyword",
+			input:     `mutation { update(avatar: null) { ok } }`,
+			field:     "update",
+			wantValue: &NullValue{},
+		},
+		{
+			name:      "Quoted null is a string",
+			input:     `mutation { update(avatar: "null") { ok } }`,
+			field:     "update",
+			wantValue: &StringValue{Value: "null"},
+		},
+		{
+			name:      "Field named null",
+			input:     `{ null(value: null) { ok } }`,
+			field:     "null",
+			wantValue: &NullValue{},
+		},
+		{
+			name:      "Null inside a list",
+			input:     `mutation { update(avatar: [null, NULL]) { ok } }`,
+			field:     "update",
+			wantValue: &ListValue{Values: []Value{&NullValue{}, &EnumValue{Value: "NULL"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := mustParseQuery(t, tt.input)
+			field := query.SelectionSet[0]
+			if field.Name != tt.field {
+				t.Errorf("Expected field %s, got %s", tt.field, field.Name)
+			}
+			if got := field.Arguments[0].Value; !valuesEqual(got, tt.wantValue) {
+				t.Errorf("Expected %s, got %s", tt.wantValue, got)
+			}
+			// null serializes unquoted, while the string "null" keeps its quotes
+			if got := query.String(); got != tt.input {
+				t.Errorf("Expected %s, got %s", tt.input, got)
+			}
+		})
+	}
+}
+
+func TestParseDirectiveNonStringArguments(t *testing.T) {
+	log.Println("Starting TestParseDirectiveNonStringArguments")
+	field := mustParseQuery(t, `query Q { feed @cache(ttl: 300, private: false) @include(if: $show) { id } }`).SelectionSet[0]
+
+	if got := field.Directives[0].Argument("ttl"); !valuesEqual(got, &IntValue{Raw: "300"}) {
+		t.Errorf("Expected ttl to be Int 300, got %v", got)
+	}
+	if got := field.Directives[0].Argument("private"); !valuesEqual(got, &BoolValue{Value: false}) {
+		t.Errorf("Expected private to be Boolean false, got %v", got)
+	}
+	if got := field.Directives[1].Argument("if"); !valuesEqual(got, &VariableValue{Name: "show"}) {
+		t.Errorf("Expected if to be Variable $show, got %v", got)
+	}
+}
+
+func TestParseDirectiveMultipleArguments(t *testing.T) {
+	log.Println("Starting TestParseDirectiveMultipleArguments")
+	want := []Argument{
+		{Name: "role", Value: &StringValue{Value: "admin"}},
+		{Name: "scope", Value: &StringValue{Value: "read"}},
+		{Name: "level", Value: &IntValue{Raw: "5"}},
+	}
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "Comma separated", input: `{ user @auth(role: "admin", scope: "read", level: 5) { name } }`},
+		{name: "Whitespace separated", input: `{ user @auth(role: "admin" scope: "read" level: 5) { name } }`},
+		{name: "Extra commas", input: `{ user @auth(,role: "admin",, scope: "read", level: 5,) { name } }`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			directive := mustParseQuery(t, tt.input).SelectionSet[0].Directives[0]
+			if len(directive.Arguments) != len(want) {
+				t.Fatalf("Expected %d arguments, got %d", len(want), len(directive.Arguments))
+			}
+			for i, arg := range directive.Arguments {
+				if arg.Name != want[i].Name || !valuesEqual(arg.Value, want[i].Value) {
+					t.Errorf("Expected argument %s: %s, got %s: %s", want[i].Name, want[i].Value, arg.Name, arg.Value)
+				}
+			}
+		})
+	}
+
+	for _, input := range []string{`{ user @auth() { name } }`, `{ user @auth(role: "admin" { name } }`} {
+		if _, err := NewParser(input).ParseQuery(); err == nil {
+			t.Errorf("Expected an error for %s, got nil", input)
+		}
+	}
+}
+
+func TestParseFieldAliases(t *testing.T) {
+	log.Println("Starting TestParseFieldAliases")
+	want := &Node{
+		Type: NodeQuery,
+		SelectionSet: []*Node{
+			{
+				Type: NodeField,
+				Name: "user",
+				SelectionSet: []*Node{
+					{Type: NodeField, Name: "name", Alias: "a"},
+					{Type: NodeField, Name: "email", Alias: "b"},
+					{
+						Type:      NodeField,
+						Name:      "avatar",
+						Alias:     "small",
+						Arguments: []Argument{{Name: "size", Value: &IntValue{Raw: "32"}}},
+					},
+				},
+			},
+		},
+	}
+
+	got := mustParseQuery(t, `{ user { a: name b: email small: avatar(size: 32) } }`)
+	if !compareNodes(got, want) {
+		t.Fatalf("Node structures not equal: %s", detailedCompare(got, want))
+	}
+
+	printed := got.Print("")
+	for _, line := range []string{"Field: a: name", "Field: b: email", "Field: small: avatar"} {
+		if !strings.Contains(printed, line) {
+			t.Errorf("Expected Print output to contain %q, got:\n%s", line, printed)
+		}
+	}
+}
+
+func TestParseFragmentSpread(t *testing.T) {
+	log.Println("Starting TestParseFragmentSpread")
+	fragment, err := NewParser(`fragment UserFields on User { id name }`).ParseFragmentDefinition()
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+	query := mustParseQuery(t, `query GetUser { user(id: "1") { ...UserFields @include(if: $full) email } }`)
+
+	want := &Node{
+		Type: NodeQuery,
+		Name: "GetUser",
+		SelectionSet: []*Node{
+			{
+				Type:      NodeField,
+				Name:      "user",
+				Arguments: []Argument{{Name: "id", Value: &StringValue{Value: "1"}}},
+				SelectionSet: []*Node{
+					{
+						Type: NodeFragmentSpread,
+						Name: "UserFields",
+						Directives: []*Node{
+							{
+								Type:      NodeDirective,
+								Name:      "include",
+								Arguments: []Argument{{Name: "if", Value: &VariableValue{Name: "full"}}},
+							},
+						},
+					},
+					{Type: NodeField, Name: "email"},
+				},
+			},
+		},
+	}
+	if !compareNodes(query, want) {
+		t.Fatalf("Node structures not equal: %s", detailedCompare(query, want))
+	}
+
+	spread := query.SelectionSet[0].SelectionSet[0]
+	if spread.Name != fragment.Name {
+		t.Errorf("Expected spread to reference %s, got %s", fragment.Name, spread.Name)
+	}
+	if loc := spread.Directives[0].Location; loc != LocationFragmentSpread {
+		t.Errorf("Expected directive location %s, got %s", LocationFragmentSpread, loc)
+	}
+}
+
+func TestParseInlineFragment(t *testing.T) {
+	log.Println("Starting TestParseInlineFragment")
+	tests := []struct {
+		name  string
+		input string
+		want  []*Node
+	}{
+		{
+			name:  "Type condition",
+			input: `{ node { ... on User { name } } }`,
+			want: []*Node{
+				{
+					Type:          NodeInlineFragment,
+					TypeCondition: "User",
+					SelectionSet:  []*Node{{Type: NodeField, Name: "name"}},
+				},
+			},
+		},
+		{
+			name:  "Directives without type condition",
+			input: `{ node { id ... @include(if: $admin) { permissions } } }`,
+			want: []*Node{
+				{Type: NodeField, Name: "id"},
+				{
+					Type: NodeInlineFragment,
+					Directives: []*Node{
+						{
+							Type:      NodeDirective,
+							Name:      "include",
+							Arguments: []Argument{{Name: "if", Value: &VariableValue{Name: "admin"}}},
+						},
+					},
+					SelectionSet: []*Node{{Type: NodeField, Name: "permissions"}},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := mustParseQuery(t, tt.input)
+			want := &Node{
+				Type:         NodeQuery,
+				SelectionSet: []*Node{{Type: NodeField, Name: "node", SelectionSet: tt.want}},
+			}
+			if !compareNodes(query, want) {
+				t.Fatalf("Node structures not equal: %s", detailedCompare(query, want))
+			}
+		})
+	}
+}
+
+func TestParseOnKeyword(t *testing.T) {
+	log.Println("Starting TestParseOnKeyword")
+	input := `
+		query on { on on: name ...on User { on(on: 1) } ...OnFields ... @skip(if: false) { on } }
+		fragment OnFields on On { on }
+	`
+	document, err := NewParser(input).ParseDocument()
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+	query, fragment := document.SelectionSet[0], document.SelectionSet[1]
+	tests := []struct {
+		name string
+		got  *Node
+		want *Node
+	}{
+		{name: "Field named on", got: query.SelectionSet[0], want: &Node{Type: NodeField, Name: "on"}},
+		{name: "Alias named on", got: query.SelectionSet[1], want: &Node{Type: NodeField, Name: "name", Alias: "on"}},
+		{
+			name: "Type condition without a space after the spread",
+			got:  query.SelectionSet[2],
+			want: &Node{Type: NodeInlineFragment, TypeCondition: "User", SelectionSet: []*Node{
+				{Type: NodeField, Name: "on", Arguments: []Argument{{Name: "on", Value: &IntValue{Raw: "1"}}}},
+			}},
+		},
+		{name: "Spread starting with On", got: query.SelectionSet[3], want: &Node{Type: NodeFragmentSpread, Name: "OnFields"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !compareNodes(tt.got, tt.want) {
+				t.Errorf("Mismatch: %s", detailedCompare(tt.got, tt.want))
+			}
+		})
+	}
+	if query.Name != "on" {
+		t.Errorf("Expected an operation named on, got %q", query.Name)
+	}
+	if inline := query.SelectionSet[4]; inline.Type != NodeInlineFragment || inline.TypeCondition != "" {
+		t.Errorf("Expected an inline fragment without a type condition, got %s %q", inline.Type, inline.TypeCondition)
+	}
+	if fragment.Name != "OnFields" || fragment.TypeCondition != "On" {
+		t.Errorf("Expected fragment OnFields on On, got %s on %s", fragment.Name, fragment.TypeCondition)
+	}
+
+	errorTests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "Fragment named on", input: `fragment on on User { id }`, want: `Fragment name cannot be "on" at line 1, column 10`},
+		{name: "Missing type after on", input: `{ ... on { id } }`, want: `Unexpected token: expected IDENT but got { at line 1, column 10`},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewParser(tt.input).ParseDocument()
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Expected error %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestParseDocument(t *testing.T) {
+	log.Println("Starting TestParseDocument")
+	input := `
+		query GetUser { user(id: "1") { ...UserFields } }
+		fragment UserFields on User { id name }
+		query Viewer { viewer { ...UserFields } }
+	`
+	document, err := NewParser(input).ParseDocument()
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+
+	want := &Node{
+		Type: NodeDocument,
+		SelectionSet: []*Node{
+			{
+				Type: NodeQuery,
+				Name: "GetUser",
+				SelectionSet: []*Node{
+					{
+						Type:         NodeField,
+						Name:         "user",
+						Arguments:    []Argument{{Name: "id", Value: &StringValue{Value: "1"}}},
+						SelectionSet: []*Node{{Type: NodeFragmentSpread, Name: "UserFields"}},
+					},
+				},
+			},
+			{
+				Type:          NodeFragmentDefinition,
+				Name:          "UserFields",
+				TypeCondition: "User",
+				SelectionSet:  []*Node{{Type: NodeField, Name: "id"}, {Type: NodeField, Name: "name"}},
+			},
+			{
+				Type: NodeQuery,
+				Name: "Viewer",
+				SelectionSet: []*Node{
+					{
+						Type:         NodeField,
+						Name:         "viewer",
+						SelectionSet: []*Node{{Type: NodeFragmentSpread, Name: "UserFields"}},
+					},
+				},
+			},
+		},
+	}
+	if !compareNodes(document, want) {
+		t.Fatalf("Node structures not equal: %s", detailedCompare(document, want))
+	}
+
+	operations := document.Operations()
+	if len(operations) != 2 || operations[0].Name != "GetUser" || operations[1].Name != "Viewer" {
+		t.Errorf("Expected operations GetUser and Viewer, got %d operations", len(operations))
+	}
+}
+
+func TestParseDocumentErrors(t *testing.T) {
+	log.Println("Starting TestParseDocumentErrors")
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "Empty document", input: ``},
+		{name: "Trailing garbage", input: `query A { a } }`},
+		{name: "Invalid second operation", input: `query A { a } query B {`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewParser(tt.input).ParseDocument()
+			if _, ok := err.(*ParseError); !ok {
+				t.Fatalf("Expected a *ParseError, got %T", err)
+			}
+		})
+	}
+}
+
+func TestParseUnexpectedCharacter(t *testing.T) {
+	log.Println("Starting TestParseUnexpectedCharacter")
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{name: "Garbage after a document", input: `query A { a } % garbage`, wantErr: `Illegal token: unexpected character '%' at line 1, column 15`},
+		{name: "Pipe between fields", input: `{ a | b }`, wantErr: `Illegal token: unexpected character '|' at line 1, column 5`},
+		{name: "Ampersand between fields", input: `{ a & b }`, wantErr: `Illegal token: unexpected character '&' at line 1, column 5`},
+		{name: "Inside arguments", input: `{ a(x: 1 ? 2) }`, wantErr: `Illegal token: unexpected character '?' at line 1, column 10`},
+		{name: "NUL after a document", input: "{ a }\x00 garbage ((", wantErr: `Illegal token: unexpected character '\x00' at line 1, column 6`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewParser(tt.input).ParseDocument()
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Expected %s, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestParseListOfObjectsArgument(t *testing.T) {
+	log.Println("Starting TestParseListOfObjectsArgument")
+	input := `mutation Tag { tagUsers(ids: [1, 2, 3], rules: [{ status: "ACTIVE", age: 30 }, { scopes: [READ, WRITE], meta: { nested: null } }]) { count } }`
+	query := mustParseQuery(t, input)
+
+	ids := &ListValue{Values: []Value{
+		&IntValue{Raw: "1"},
+		&IntValue{Raw: "2"},
+		&IntValue{Raw: "3"},
+	}}
+	rules := &ListValue{Values: []Value{
+		&ObjectValue{Fields: []*ObjectField{
+			{Name: "status", Value: &StringValue{Value: "ACTIVE"}},
+			{Name: "age", Value: &IntValue{Raw: "30"}},
+		}},
+		&ObjectValue{Fields: []*ObjectField{
+			{Name: "scopes", Value: &ListValue{Values: []Value{
+				&EnumValue{Value: "READ"},
+				&EnumValue{Value: "WRITE"},
+			}}},
+			{Name: "meta", Value: &ObjectValue{Fields: []*ObjectField{
+				{Name: "nested", Value: &NullValue{}},
+			}}},
+		}},
+	}}
+
+	field := query.SelectionSet[0]
+	if got := field.Argument("ids"); !valuesEqual(got, ids) {
+		t.Errorf("Expected ids %s, got %s", ids, got)
+	}
+	if got := field.Argument("rules"); !valuesEqual(got, rules) {
+		t.Errorf("Expected rules %s, got %s", rules, got)
+	}
+}
+
+func TestPrintPreservesArgumentOrder(t *testing.T) {
+	log.Println("Starting TestPrintPreservesArgumentOrder")
+	input := `query Q { items(z: 1, a: 2, m: 3, b: 4, y: 5) @cache(ttl: 60, scope: PRIVATE) { id } }`
+	want := "Query: Q\n" +
+		"  Field: items\n" +
+		"    Arg: z = 1\n" +
+		"    Arg: a = 2\n" +
+		"    Arg: m = 3\n" +
+		"    Arg: b = 4\n" +
+		"    Arg: y = 5\n" +
+		"    Directive: @cache\n" +
+		"      Arg: ttl = 60\n" +
+		"      Arg: scope = PRIVATE\n" +
+		"    Field: id\n"
+
+	for run := 0; run < 20; run++ {
+		if got := mustParseQuery(t, input).Print(""); got != want {
+			t.Fatalf("Run %d: expected\n%s\ngot\n%s", run, want, got)
+		}
+	}
+}
+
+func TestParseDuplicateArgument(t *testing.T) {
+	log.Println("Starting TestParseDuplicateArgument")
+	_, err := NewParser(`query Q { user(id: 1, id: 2) { name } }`).ParseQuery()
+	if err == nil || !strings.Contains(err.Error(), `Duplicate argument "id"`) {
+		t.Errorf("Expected duplicate argument error, got %v", err)
+	}
+}
+
+func TestOperationNames(t *testing.T) {
+	log.Println("Starting TestOperationNames")
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{name: "Named", input: `query GetUser { user { id } }`, want: []string{"GetUser"}},
+		{name: "Anonymous", input: `query { user { id } }`, want: []string{""}},
+		{name: "Shorthand", input: `{ user { id } }`, want: []string{""}},
+		{
+			name:  "Multiple operations",
+			input: `query A { a } fragment F on T { f } mutation B { b } subscription { c }`,
+			want:  []string{"A", "B", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := OperationNames(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := OperationNames(`query {`); err == nil {
+		t.Errorf("Expected an error for an invalid document")
+	}
+}
+
+func TestOperationType(t *testing.T) {
+	log.Println("Starting TestOperationType")
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "Query", input: `query Q { a }`, want: "query"},
+		{name: "Mutation", input: `mutation M { a }`, want: "mutation"},
+		{name: "Subscription", input: `subscription S { a }`, want: "subscription"},
+		{name: "Shorthand", input: `{ a }`, want: "query"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mustParseQuery(t, tt.input)
+			if got.OperationType != tt.want {
+				t.Errorf("Expected operation type %q, got %q", tt.want, got.OperationType)
+			}
+			if got.SelectionSet[0].OperationType != "" {
+				t.Errorf("Expected fields to have no operation type, got %q", got.SelectionSet[0].OperationType)
+			}
+		})
+	}
+
+	mutation := &Node{Type: NodeMutation, OperationType: "mutation", Name: "M", SelectionSet: []*Node{{Type: NodeField, Name: "a"}}}
+	if got, want := mutation.String(), `mutation M { a }`; got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestParseDirectiveCompositeArguments(t *testing.T) {
+	log.Println("Starting TestParseDirectiveCompositeArguments")
+	input := `{ user @transform(fields: ["a", "b"], mode: UPPER) @auth(rule: {role: "admin", scopes: [READ, WRITE]}, strict: true) { name } }`
+	tests := []struct {
+		name      string
+		directive int
+		argument  string
+		want      Value
+	}{
+		{
+			name:      "List value",
+			directive: 0,
+			argument:  "fields",
+			want: &ListValue{Values: []Value{
+				&StringValue{Value: "a"},
+				&StringValue{Value: "b"},
+			}},
+		},
+		{
+			name:      "Object value",
+			directive: 1,
+			argument:  "rule",
+			want: &ObjectValue{Fields: []*ObjectField{
+				{Name: "role", Value: &StringValue{Value: "admin"}},
+				{Name: "scopes", Value: &ListValue{Values: []Value{
+					&EnumValue{Value: "READ"},
+					&EnumValue{Value: "WRITE"},
+				}}},
+			}},
+		},
+	}
+
+	query := mustParseQuery(t, input)
+	directives := query.SelectionSet[0].Directives
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := directives[tt.directive].Argument(tt.argument); !valuesEqual(got, tt.want) {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	// Arguments and object fields serialize in the order they were written
+	if got := query.String(); got != input {
+		t.Errorf("Expected %s, got %s", input, got)
+	}
+}
+
+func TestParseSelectionSetEdgeCases(t *testing.T) {
+	log.Println("Starting TestParseSelectionSetEdgeCases")
+	query := mustParseQuery(t, `query X {}`)
+	if query.Name != "X" || len(query.SelectionSet) != 0 {
+		t.Errorf("Expected query X with an empty selection set, got %s", query.Print(""))
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "Number in selection set",
+			input: `query X { 123 }`,
+			want:  `Unexpected token in selection set: expected a field, fragment, or } but got INT "123" at line 1, column 11`,
+		},
+		{
+			name:  "Stray token after fields",
+			input: `query X { a b : }`,
+			want:  `Unexpected token: expected IDENT but got } at line 1, column 17`,
+		},
+		{
+			name:  "Unclosed selection set",
+			input: `query X { a`,
+			want:  `Unexpected token: expected } but got EOF at line 1, column 12`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewParser(tt.input).ParseQuery()
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Expected error %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestParserReset(t *testing.T) {
+	log.Println("Starting TestParserReset")
+	p := NewParserWithOptions(`query A { a b c }`, Options{MaxTokens: 12})
+	if _, err := p.ParseQuery(); err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+
+	// A failed parse leaves the parser mid-input; Reset must recover from that too
+	p.Reset(`query B { broken(`)
+	if _, err := p.ParseQuery(); err == nil {
+		t.Fatalf("Expected a parse error")
+	}
+
+	p.Reset(`query C { x y z }`)
+	got, err := p.ParseQuery()
+	if err != nil {
+		t.Fatalf("Unexpected parse error after reset: %s", err)
+	}
+	if want := `query C { x y z }`; got.String() != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+	if p.options.MaxTokens != 12 {
+		t.Errorf("Expected options to survive Reset, got %+v", p.options)
+	}
+
+	// Resetting to an empty input releases the previous input, as pooled parsers do
+	p.Reset("")
+	if p.input != "" || p.curr.Type != lexer.TokenEOF || p.tokens != 0 || p.comments != nil {
+		t.Errorf("Expected a parser at the end of empty input, got %+v", p)
+	}
+}
+
+const benchmarkQuery = `query GetUser($id: ID!) { user(id: $id) { id name friends(first: 10) { name avatar(size: 32) } } }`
+
+func BenchmarkNewParser(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewParser(benchmarkQuery).ParseQuery(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParserReset(b *testing.B) {
+	b.ReportAllocs()
+	p := NewParser(benchmarkQuery)
+	for i := 0; i < b.N; i++ {
+		p.Reset(benchmarkQuery)
+		if _, err := p.ParseQuery(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// benchmarkFixtures returns representative documents of increasing size for benchmarks
+func benchmarkFixtures() []struct{ name, input string } {
+	return []struct{ name, input string }{
+		{name: "Small", input: `{ viewer { id name } }`},
+		{name: "Medium", input: benchmarkQuery + ` fragment UserFields on User @cache(ttl: 60) { id name email avatar(size: 64) ... on Admin { permissions(scope: "all") } }`},
+		{name: "DeeplyNested", input: nestedQuery(100)},
+		{name: "LargeGenerated", input: generatedQuery(500)},
+	}
+}
+
+// nestedQuery returns a query whose selection sets are nested depth levels deep
+func nestedQuery(depth int) string {
+	return "query Deep " + strings.Repeat("{ a ", depth) + "{ leaf }" + strings.Repeat(" }", depth)
+}
+
+// generatedQuery returns a single operation selecting n fields, each with arguments,
+// a directive, and a nested selection set
+func generatedQuery(n int) string {
+	var b strings.Builder
+	b.WriteString("query Large($id: ID!, $first: Int = 10) {")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, ` field%d: item(id: $id, index: %d, filter: { tags: ["a", "b"], active: true }) @include(if: true) { id name value(first: $first) }`, i, i)
+	}
+	b.WriteString(" }")
+	return b.String()
+}
+
+func BenchmarkParseQuery(b *testing.B) {
+	for _, fixture := range benchmarkFixtures() {
+		b.Run(fixture.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(fixture.input)))
+			for i := 0; i < b.N; i++ {
+				if _, err := NewParser(fixture.input).ParseQuery(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkParseDocument(b *testing.B) {
+	for _, fixture := range benchmarkFixtures() {
+		b.Run(fixture.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(fixture.input)))
+			for i := 0; i < b.N; i++ {
+				if _, err := NewParser(fixture.input).ParseDocument(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+import "slices"
+
+// redactedPlaceholder replaces the values of redacted arguments
+const redactedPlaceholder = "***"
+
+// Redact returns a copy of the tree rooted at n in which the literal values of the
+// named arguments are replaced by ***, so queries can be logged without leaking
+// data such as emails or tokens. Field and directive arguments are redacted at
+// every depth, as are input object fields with a matching name. Variable
+// references are kept because they carry no data. The copy shares nothing with
+// the original tree, which is not modified.
+func Redact(n *Node, argNames []string) *Node {
+	redacted := n.Clone()
+	redactNode(redacted, argNames)
+	return redacted
+}
+
+// redactNode redacts the named arguments of n, its directives, and its selections in place
+func redactNode(n *Node, names []string) {
+	if n == nil {
+		return
+	}
+	for i, arg := range n.Arguments {
+		n.Arguments[i].Value = redactValue(arg.Value, slices.Contains(names, arg.Name), names)
+	}
+	for _, directive := range n.Directives {
+		redactNode(directive, names)
+	}
+	for _, child := range n.SelectionSet {
+		redactNode(child, names)
+	}
+}
+
+// redactValue returns a new placeholder in place of v if redact is set, and
+// otherwise redacts the object fields with one of the given names within v in place
+func redactValue(v Value, redact bool, names []string) Value {
+	if _, ok := v.(*VariableValue); ok {
+		return v
+	}
+	if redact {
+		return &EnumValue{Value: redactedPlaceholder}
+	}
+	switch v := v.(type) {
+	case *ListValue:
+		for i, item := range v.Values {
+			v.Values[i] = redactValue(item, false, names)
+		}
+	case *ObjectValue:
+		for _, field := range v.Fields {
+			field.Value = redactValue(field.Value, slices.Contains(names, field.Name), names)
+		}
+	}
+	return v
+}
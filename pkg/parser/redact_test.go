@@ -0,0 +1,76 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+import (
+	"log"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	log.Println("Starting TestRedact")
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "Nested field",
+			input: `{ org { members(email: "ada@example.com", first: 10) { name } } }`,
+			want:  `{ org { members(email: ***, first: 10) { name } } }`,
+		},
+		{
+			name:  "Directive argument",
+			input: `{ me @auth(token: "s3cret", role: ADMIN) { id } }`,
+			want:  `{ me @auth(token: ***, role: ADMIN) { id } }`,
+		},
+		{
+			name:  "Object and list values",
+			input: `mutation { invite(input: {emails: ["a@example.com"], team: {email: "t@example.com", name: "Core"}}) { ok } }`,
+			want:  `mutation { invite(input: {emails: ["a@example.com"], team: {email: ***, name: "Core"}}) { ok } }`,
+		},
+		{
+			name:  "Whole object",
+			input: `mutation { signup(token: {value: "abc"}) { ok } }`,
+			want:  `mutation { signup(token: ***) { ok } }`,
+		},
+		{
+			name:  "Variables are kept",
+			input: `query Q($email: String) { user(email: $email) { ... on Admin { audit(email: "x@example.com") } } }`,
+			want:  `query Q($email: String) { user(email: $email) { ... on Admin { audit(email: ***) } } }`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := mustParseQuery(t, tt.input)
+			original := query.String()
+			if got := Redact(query, []string{"email", "token"}).String(); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+			if got := query.String(); got != original {
+				t.Errorf("Expected the original to be unmodified, got %s", got)
+			}
+		})
+	}
+}
+
+func TestRedactSharesNothing(t *testing.T) {
+	log.Println("Starting TestRedactSharesNothing")
+	input := `{ login(email: "ada@example.com", remember: true, options: {token: "abc", ttl: 60}) { id } }`
+	query := mustParseQuery(t, input)
+
+	redacted := Redact(query, []string{"email", "token"})
+	login := redacted.SelectionSet[0]
+	login.Argument("remember").(*BoolValue).Value = false
+	login.Argument("options").(*ObjectValue).Fields[1].Value.(*IntValue).Raw = "0"
+	login.Argument("email").(*EnumValue).Value = "LEAK"
+	login.Argument("options").(*ObjectValue).Fields[0].Value.(*EnumValue).Value = "LEAK"
+
+	if got := query.String(); got != mustParseQuery(t, input).String() {
+		t.Errorf("Expected the original to be unmodified, got %s", got)
+	}
+	want := `{ login(email: ***, remember: true, options: {token: ***, ttl: 60}) { id } }`
+	if got := Redact(query, []string{"email", "token"}).String(); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
@@ -0,0 +1,230 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+import (
+	"slices"
+	"strings"
+)
+
+// DefaultIndent is the indentation width used when PrintOptions.Indent is zero
+const DefaultIndent = 2
+
+// PrintOptions configures how Serialize lays out GraphQL
+type PrintOptions struct {
+	// Indent is the number of spaces each nesting level is indented by.
+	// Zero or a negative value means DefaultIndent. It is ignored in compact mode.
+	Indent int
+
+	// Compact renders everything on a single line, as String does
+	Compact bool
+}
+
+// String renders the node as valid GraphQL on a single line, for example
+// query Name @dir { alias: field(arg: value) { ... } }. Parsing the result
+// yields a structurally equal node.
+func (n *Node) String() string {
+	return n.Serialize(PrintOptions{Compact: true})
+}
+
+// Serialize renders the node as valid GraphQL laid out according to opts. Unless
+// opts.Compact is set, each selection goes on its own line, indented by its
+// nesting level, and document definitions are separated by a blank line.
+// Parsing the result yields a structurally equal node.
+func (n *Node) Serialize(opts PrintOptions) string {
+	indent := opts.Indent
+	if indent <= 0 {
+		indent = DefaultIndent
+	}
+	p := &printer{compact: opts.Compact, indent: strings.Repeat(" ", indent)}
+	p.writeNode(n)
+	return p.b.String()
+}
+
+// Canonicalize parses a GraphQL document and re-serializes it in canonical
+// compact form: single-line, with field and directive arguments and input object
+// fields sorted by name. Queries that differ only in formatting or argument order
+// canonicalize to the same string. Unlike Node.Normalize, literal values are kept.
+func Canonicalize(query string) (string, error) {
+	document, err := NewParser(query).ParseDocument()
+	if err != nil {
+		return "", err
+	}
+	return sortArguments(document).String(), nil
+}
+
+// sortArguments returns a copy of the tree with arguments and object fields sorted by name
+func sortArguments(n *Node) *Node {
+	sorted := *n
+	sorted.Arguments = make([]Argument, len(n.Arguments))
+	for i, arg := range n.Arguments {
+		sorted.Arguments[i] = Argument{Name: arg.Name, Value: sortObjectFields(arg.Value)}
+	}
+	slices.SortFunc(sorted.Arguments, func(a, b Argument) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	sorted.Directives = make([]*Node, len(n.Directives))
+	for i, directive := range n.Directives {
+		sorted.Directives[i] = sortArguments(directive)
+	}
+	sorted.SelectionSet = make([]*Node, len(n.SelectionSet))
+	for i, child := range n.SelectionSet {
+		sorted.SelectionSet[i] = sortArguments(child)
+	}
+	return &sorted
+}
+
+// sortObjectFields returns a copy of v with the fields of every object value sorted by name
+func sortObjectFields(v Value) Value {
+	switch v := v.(type) {
+	case *ListValue:
+		sorted := &ListValue{Values: make([]Value, len(v.Values))}
+		for i, item := range v.Values {
+			sorted.Values[i] = sortObjectFields(item)
+		}
+		return sorted
+	case *ObjectValue:
+		sorted := &ObjectValue{Fields: make([]*ObjectField, len(v.Fields))}
+		for i, field := range v.Fields {
+			sorted.Fields[i] = &ObjectField{Name: field.Name, Value: sortObjectFields(field.Value)}
+		}
+		slices.SortFunc(sorted.Fields, func(a, b *ObjectField) int {
+			return strings.Compare(a.Name, b.Name)
+		})
+		return sorted
+	default:
+		return v
+	}
+}
+
+// printer accumulates the GraphQL rendering of a tree
+type printer struct {
+	b       strings.Builder
+	compact bool
+	indent  string // Indentation for a single nesting level
+	level   int    // Nesting level of the selection being written
+}
+
+// writeNode appends the GraphQL rendering of the node
+func (p *printer) writeNode(n *Node) {
+	switch n.Type {
+	case NodeDocument:
+		separator := "\n\n"
+		if p.compact {
+			separator = " "
+		}
+		for i, definition := range n.SelectionSet {
+			if i > 0 {
+				p.b.WriteString(separator)
+			}
+			p.writeNode(definition)
+		}
+	case NodeQuery, NodeMutation, NodeSubscription:
+		// An anonymous query without directives uses the { ... } shorthand
+		if n.Type != NodeQuery || n.Name != "" || len(n.VariableDefinitions) > 0 || len(n.Directives) > 0 {
+			p.b.WriteString(n.operationKeyword())
+			if n.Name != "" {
+				p.b.WriteString(" " + n.Name)
+			}
+			p.writeVariableDefinitions(n.VariableDefinitions)
+			p.writeDirectives(n.Directives)
+			p.b.WriteByte(' ')
+		}
+		p.writeSelectionSet(n.SelectionSet)
+	case NodeFragmentDefinition:
+		p.b.WriteString("fragment " + n.Name + " on " + n.TypeCondition)
+		p.writeDirectives(n.Directives)
+		p.b.WriteByte(' ')
+		p.writeSelectionSet(n.SelectionSet)
+	case NodeFragmentSpread:
+		p.b.WriteString("..." + n.Name)
+		p.writeDirectives(n.Directives)
+	case NodeInlineFragment:
+		p.b.WriteString("...")
+		if n.TypeCondition != "" {
+			p.b.WriteString(" on " + n.TypeCondition)
+		}
+		p.writeDirectives(n.Directives)
+		p.b.WriteByte(' ')
+		p.writeSelectionSet(n.SelectionSet)
+	case NodeDirective:
+		p.b.WriteString("@" + n.Name)
+		p.writeArguments(n.Arguments)
+	default:
+		if n.Alias != "" {
+			p.b.WriteString(n.Alias + ": ")
+		}
+		p.b.WriteString(n.Name)
+		p.writeArguments(n.Arguments)
+		p.writeDirectives(n.Directives)
+		if len(n.SelectionSet) > 0 {
+			p.b.WriteByte(' ')
+			p.writeSelectionSet(n.SelectionSet)
+		}
+	}
+}
+
+// writeArguments appends a parenthesized argument list, or nothing if args is empty
+func (p *printer) writeArguments(args []Argument) {
+	if len(args) == 0 {
+		return
+	}
+	p.b.WriteByte('(')
+	for i, arg := range args {
+		if i > 0 {
+			p.b.WriteString(", ")
+		}
+		p.b.WriteString(arg.Name + ": " + arg.Value.String())
+	}
+	p.b.WriteByte(')')
+}
+
+// writeVariableDefinitions appends a parenthesized variable definition list, or nothing if there are none
+func (p *printer) writeVariableDefinitions(definitions []*VariableDefinition) {
+	if len(definitions) == 0 {
+		return
+	}
+	p.b.WriteByte('(')
+	for i, definition := range definitions {
+		if i > 0 {
+			p.b.WriteString(", ")
+		}
+		p.b.WriteString(definition.String())
+	}
+	p.b.WriteByte(')')
+}
+
+// writeDirectives appends each directive preceded by a space
+func (p *printer) writeDirectives(directives []*Node) {
+	for _, directive := range directives {
+		p.b.WriteByte(' ')
+		p.writeNode(directive)
+	}
+}
+
+// writeSelectionSet appends a braced selection set, either space-separated on one
+// line or with one indented selection per line
+func (p *printer) writeSelectionSet(selections []*Node) {
+	if p.compact {
+		p.b.WriteString("{ ")
+		for _, selection := range selections {
+			p.writeNode(selection)
+			p.b.WriteByte(' ')
+		}
+		p.b.WriteByte('}')
+		return
+	}
+	if len(selections) == 0 {
+		p.b.WriteString("{}")
+		return
+	}
+
+	p.b.WriteString("{\n")
+	p.level++
+	for _, selection := range selections {
+		p.b.WriteString(strings.Repeat(p.indent, p.level))
+		p.writeNode(selection)
+		p.b.WriteByte('\n')
+	}
+	p.level--
+	p.b.WriteString(strings.Repeat(p.indent, p.level) + "}")
+}
@@ -0,0 +1,317 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+import (
+	"log"
+	"testing"
+)
+
+func TestNodeString(t *testing.T) {
+	log.Println("Starting TestNodeString")
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "Named query with arguments",
+			input: `query GetUser { user(id: "123", active: true) { name } }`,
+			want:  `query GetUser { user(id: "123", active: true) { name } }`,
+		},
+		{
+			name:  "Shorthand query",
+			input: `{ me { id } }`,
+			want:  `{ me { id } }`,
+		},
+		{
+			name:  "Aliases and directives",
+			input: `query Q @live { small: avatar(size: 32) @cache(ttl: 60) @include(if: $big) }`,
+			want:  `query Q @live { small: avatar(size: 32) @cache(ttl: 60) @include(if: $big) }`,
+		},
+		{
+			name:  "Fragments",
+			input: `{ node { ...UserFields @skip(if: $lite) ... on Admin { permissions } ... @defer { slow } } }`,
+			want:  `{ node { ...UserFields @skip(if: $lite) ... on Admin { permissions } ... @defer { slow } } }`,
+		},
+		{
+			name:  "Subscription with directives",
+			input: `subscription OnMessage($room: ID!) @live(throttle: 100) { messageAdded(room: $room) @include(if: true) { id text } }`,
+			want:  `subscription OnMessage($room: ID!) @live(throttle: 100) { messageAdded(room: $room) @include(if: true) { id text } }`,
+		},
+		{
+			name:  "Mutation with object value",
+			input: `mutation { update(input: {name: "x", tags: [A B]}) { ok } }`,
+			want:  `mutation { update(input: {name: "x", tags: [A, B]}) { ok } }`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mustParseQuery(t, tt.input).String(); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNodeStringRoundTrip(t *testing.T) {
+	log.Println("Starting TestNodeStringRoundTrip")
+	input := `
+		query Feed($first: Int = 10, $video: Boolean!) @live {
+			viewer { id }
+			posts: feed(first: 10, filter: { tags: ["go", "graphql"], author: { name: """Ada "Countess" Lovelace""" } }) @cache(ttl: 300) {
+				...PostFields
+				... on Video @include(if: $video) { duration }
+			}
+		}
+		fragment PostFields on Post @tracked { title body }
+	`
+
+	original, err := NewParser(input).ParseDocument()
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+	serialized := original.String()
+	reparsed, err := NewParser(serialized).ParseDocument()
+	if err != nil {
+		t.Fatalf("Could not re-parse %s: %s", serialized, err)
+	}
+	if !compareNodes(reparsed, original) {
+		t.Fatalf("Round trip changed the document: %s", detailedCompare(reparsed, original))
+	}
+}
+
+// roundTripCorpus lists valid documents that must survive serializing and
+// re-parsing unchanged. Add a case here whenever a serializer bug is fixed.
+var roundTripCorpus = []struct {
+	name  string
+	input string
+}{
+	{name: "Shorthand query", input: `{ me { id } }`},
+	{name: "Named query", input: `query GetUser { user { name } }`},
+	{name: "Aliases", input: `{ small: avatar(size: 32) large: avatar(size: 256) me: viewer { id: uuid } }`},
+	{name: "Multiple arguments", input: `{ search(term: "go", first: 10, after: $cursor, exact: false, score: 1.5, sort: DESC, owner: null) { id } }`},
+	{name: "Composite arguments", input: `{ update(input: { tags: ["a", "b"], nested: { list: [[1, 2], []], empty: {} } }) { ok } }`},
+	{name: "Strings needing escapes", input: `{ echo(text: "tab\tquote\"backslash\\ unicode \u00e9") block: echo(text: """multi
+line "quoted" text""") }`},
+	{name: "Variable definitions", input: `query Q($id: ID!, $first: Int = 10, $tags: [String!]! = ["x"], $filter: Filter = { a: 1 }) { user(id: $id) { name } }`},
+	{name: "Operation directives", input: `query Q @live @cache(ttl: 60) { a }`},
+	{name: "Nested directives", input: `{ user @auth(rule: { role: "admin", scopes: [READ, WRITE] }) { posts @include(if: $posts) { title @uppercase comments @skip(if: $lite) { id } } } }`},
+	{name: "Mutation", input: `mutation Like($id: ID!) { like(postId: $id) { post { likes } } }`},
+	{name: "Subscription", input: `subscription OnMessage($room: ID!) @live(throttle: 100) { messageAdded(room: $room) { id text } }`},
+	{name: "Fragment spreads", input: `{ user { ...UserFields ...Extra @include(if: $extra) } } fragment UserFields on User { id name } fragment Extra on User @tracked { email }`},
+	{name: "Inline fragments", input: `{ node { ... on User { name } ... @defer { slow } ... on Admin @include(if: $admin) { permissions } } }`},
+	{name: "Multiple operations", input: `query A { a } mutation B { b } subscription C { c } fragment F on T { f }`},
+	{name: "Deep nesting", input: `{ a { b { c { d { e { f { g } } } } } } }`},
+}
+
+func TestSerializeRoundTripCorpus(t *testing.T) {
+	log.Println("Starting TestSerializeRoundTripCorpus")
+	layouts := []struct {
+		name string
+		opts PrintOptions
+	}{
+		{name: "Compact", opts: PrintOptions{Compact: true}},
+		{name: "Indented", opts: PrintOptions{}},
+		{name: "Wide indent", opts: PrintOptions{Indent: 4}},
+	}
+
+	for _, tt := range roundTripCorpus {
+		original, err := NewParser(tt.input).ParseDocument()
+		if err != nil {
+			t.Fatalf("Unexpected parse error in corpus case %q: %s", tt.name, err)
+		}
+		for _, layout := range layouts {
+			t.Run(tt.name+"/"+layout.name, func(t *testing.T) {
+				serialized := original.Serialize(layout.opts)
+				reparsed, err := NewParser(serialized).ParseDocument()
+				if err != nil {
+					t.Fatalf("Could not re-parse %s: %s", serialized, err)
+				}
+				if !compareNodes(reparsed, original) {
+					t.Fatalf("Round trip changed the document: %s", detailedCompare(reparsed, original))
+				}
+				// Serializing the re-parsed document must be stable
+				if again := reparsed.Serialize(layout.opts); again != serialized {
+					t.Errorf("Expected %s, got %s", serialized, again)
+				}
+			})
+		}
+	}
+}
+
+func TestNodeStringPreservesValueKinds(t *testing.T) {
+	log.Println("Starting TestNodeStringPreservesValueKinds")
+	tests := []struct {
+		name     string
+		input    string
+		wantKind ValueKind
+	}{
+		{name: "Quoted number stays a string", input: `{ user(id: "123") { name } }`, wantKind: ValueString},
+		{name: "Bare number stays an int", input: `{ user(id: 123) { name } }`, wantKind: ValueInt},
+		{name: "Bare decimal stays a float", input: `{ user(id: 1.5) { name } }`, wantKind: ValueFloat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serialized := mustParseQuery(t, tt.input).String()
+			if serialized != tt.input {
+				t.Errorf("Expected %s, got %s", tt.input, serialized)
+			}
+			reparsed := mustParseQuery(t, serialized)
+			if got := reparsed.SelectionSet[0].Argument("id"); got == nil || got.Kind() != tt.wantKind {
+				t.Errorf("Expected id of kind %s after round trip, got %+v", tt.wantKind, got)
+			}
+		})
+	}
+
+	quoted := mustParseQuery(t, `{ user(id: "123") { name } }`)
+	bare := mustParseQuery(t, `{ user(id: 123) { name } }`)
+	if compareNodes(quoted, bare) {
+		t.Errorf(`Expected id: "123" and id: 123 to parse differently`)
+	}
+}
+
+func TestNodeSerialize(t *testing.T) {
+	log.Println("Starting TestNodeSerialize")
+	input := `
+		query Feed($first: Int = 10) @live {
+			posts(first: $first) @cache(ttl: 60) { title ...PostFields ... on Video { duration } }
+		}
+		fragment PostFields on Post { body }
+	`
+	tests := []struct {
+		name string
+		opts PrintOptions
+		want string
+	}{
+		{
+			name: "Four spaces",
+			opts: PrintOptions{Indent: 4},
+			want: `query Feed($first: Int = 10) @live {
+    posts(first: $first) @cache(ttl: 60) {
+        title
+        ...PostFields
+        ... on Video {
+            duration
+        }
+    }
+}
+
+fragment PostFields on Post {
+    body
+}`,
+		},
+		{
+			name: "Default indent",
+			opts: PrintOptions{},
+			want: `query Feed($first: Int = 10) @live {
+  posts(first: $first) @cache(ttl: 60) {
+    title
+    ...PostFields
+    ... on Video {
+      duration
+    }
+  }
+}
+
+fragment PostFields on Post {
+  body
+}`,
+		},
+		{
+			name: "Compact",
+			opts: PrintOptions{Indent: 4, Compact: true},
+			want: `query Feed($first: Int = 10) @live { posts(first: $first) @cache(ttl: 60) { title ...PostFields ... on Video { duration } } } fragment PostFields on Post { body }`,
+		},
+	}
+
+	document, err := NewParser(input).ParseDocument()
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := document.Serialize(tt.opts)
+			if got != tt.want {
+				t.Fatalf("Expected\n%s\ngot\n%s", tt.want, got)
+			}
+			reparsed, err := NewParser(got).ParseDocument()
+			if err != nil {
+				t.Fatalf("Could not re-parse %s: %s", got, err)
+			}
+			if !compareNodes(reparsed, document) {
+				t.Errorf("Round trip changed the document: %s", detailedCompare(reparsed, document))
+			}
+		})
+	}
+
+	// A negative indent falls back to the default rather than panicking
+	if got, want := document.Serialize(PrintOptions{Indent: -1}), document.Serialize(PrintOptions{}); got != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+
+	if got := mustParseQuery(t, `query X {}`).Serialize(PrintOptions{}); got != "query X {}" {
+		t.Errorf("Expected an empty selection set to print as {}, got %s", got)
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	log.Println("Starting TestCanonicalize")
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want string
+	}{
+		{
+			name: "Whitespace and commas",
+			a:    `query Q { user(id: 1) { name email } }`,
+			b:    "query   Q {\n  user( id : 1 ) {\n    name,\n    email\n  }\n}\n",
+			want: `query Q { user(id: 1) { name email } }`,
+		},
+		{
+			name: "Argument order",
+			a:    `{ posts(first: 10, after: "x") @cache(ttl: 60, scope: PUBLIC) { title } }`,
+			b:    `{ posts(after: "x", first: 10) @cache(scope: PUBLIC, ttl: 60) { title } }`,
+			want: `{ posts(after: "x", first: 10) @cache(scope: PUBLIC, ttl: 60) { title } }`,
+		},
+		{
+			name: "Object field order",
+			a:    `mutation { update(input: {name: "x", tags: [{b: 1, a: 2}]}) { ok } }`,
+			b:    "mutation {\n  update(input: { tags: [{ a: 2 b: 1 }] name: \"x\" }) { ok }\n}",
+			want: `mutation { update(input: {name: "x", tags: [{a: 2, b: 1}]}) { ok } }`,
+		},
+		{
+			name: "Documents with comments",
+			a:    `query A { a } fragment F on T { f }`,
+			b:    "# first\nquery A {\n  a\n}\n\n# shared\nfragment F on T {\n  f\n}",
+			want: `query A { a } fragment F on T { f }`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := Canonicalize(tt.a)
+			if err != nil {
+				t.Fatalf("Unexpected error canonicalizing %s: %s", tt.a, err)
+			}
+			b, err := Canonicalize(tt.b)
+			if err != nil {
+				t.Fatalf("Unexpected error canonicalizing %s: %s", tt.b, err)
+			}
+			if a != tt.want || b != tt.want {
+				t.Errorf("Expected both to canonicalize to %s, got %s and %s", tt.want, a, b)
+			}
+		})
+	}
+
+	if _, err := Canonicalize(`query {`); err == nil {
+		t.Errorf("Expected an error for invalid input")
+	}
+	one, _ := Canonicalize(`{ user(id: 1) { name } }`)
+	two, _ := Canonicalize(`{ user(id: 2) { name } }`)
+	if one == two {
+		t.Errorf("Expected different literals to canonicalize differently, got %s for both", one)
+	}
+}
@@ -0,0 +1,39 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+import "slices"
+
+// StripDirectives returns a copy of the node with the named directives removed at
+// every level, including variable definitions, or with all directives removed if no
+// names are given. The copy shares nothing with the original tree, which is not
+// modified.
+func (n *Node) StripDirectives(names ...string) *Node {
+	stripped := n.Clone()
+	stripNode(stripped, names)
+	return stripped
+}
+
+// stripNode removes the named directives from n, its variable definitions, and its selections in place
+func stripNode(n *Node, names []string) {
+	if n == nil {
+		return
+	}
+	n.Directives = stripDirectiveList(n.Directives, names)
+	for _, definition := range n.VariableDefinitions {
+		definition.Directives = stripDirectiveList(definition.Directives, names)
+	}
+	for _, child := range n.SelectionSet {
+		stripNode(child, names)
+	}
+}
+
+// stripDirectiveList returns the directives whose names are not in names, or none if names is empty
+func stripDirectiveList(directives []*Node, names []string) []*Node {
+	var kept []*Node
+	for _, directive := range directives {
+		if len(names) > 0 && !slices.Contains(names, directive.Name) {
+			kept = append(kept, directive)
+		}
+	}
+	return kept
+}
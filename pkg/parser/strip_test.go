@@ -0,0 +1,63 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+import (
+	"log"
+	"testing"
+)
+
+func TestStripDirectives(t *testing.T) {
+	log.Println("Starting TestStripDirectives")
+	input := `query Q($id: ID @persist) @cache(ttl: 1) { user(id: $id) @cache(ttl: 2) @include(if: $full) { name @cache(ttl: 3) ... on Admin @persist { role } } }`
+	tests := []struct {
+		name  string
+		strip []string
+		want  string
+	}{
+		{
+			name:  "Named directive",
+			strip: []string{"cache"},
+			want:  `query Q($id: ID @persist) { user(id: $id) @include(if: $full) { name ... on Admin @persist { role } } }`,
+		},
+		{
+			name:  "Several names",
+			strip: []string{"cache", "persist"},
+			want:  `query Q($id: ID) { user(id: $id) @include(if: $full) { name ... on Admin { role } } }`,
+		},
+		{
+			name: "All directives",
+			want: `query Q($id: ID) { user(id: $id) { name ... on Admin { role } } }`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := mustParseQuery(t, input)
+			if got := query.StripDirectives(tt.strip...).String(); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+			if got := query.String(); got != input {
+				t.Errorf("Expected the original to be unmodified, got %s", got)
+			}
+		})
+	}
+}
+
+func TestStripDirectivesSharesNothing(t *testing.T) {
+	log.Println("Starting TestStripDirectivesSharesNothing")
+	input := `query Q($id: ID = 1 @persist) { user(id: $id, filter: {tags: ["a"]}) @cache(ttl: 2) @include(if: $full) { name } }`
+	query := mustParseQuery(t, input)
+
+	stripped := query.StripDirectives("cache")
+	user := stripped.SelectionSet[0]
+	user.Arguments[0].Name = "changed"
+	user.Argument("filter").(*ObjectValue).Fields[0].Value.(*ListValue).Values[0].(*StringValue).Value = "changed"
+	user.Directives[0].Arguments[0].Value.(*VariableValue).Name = "changed"
+	user.SelectionSet[0].Name = "changed"
+	stripped.VariableDefinitions[0].DefaultValue.(*IntValue).Raw = "2"
+	stripped.VariableDefinitions[0].Type.Name = "String"
+
+	if got := query.String(); got != input {
+		t.Errorf("Expected the original to be unmodified, got %s", got)
+	}
+}
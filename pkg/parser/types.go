@@ -0,0 +1,60 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+import "github.com/tom/graphqlinsights/pkg/lexer"
+
+// TypeRef represents a type reference such as ID!, [String] or [[Int!]]!
+type TypeRef struct {
+	Name    string   // Named type; empty for list types
+	OfType  *TypeRef // Element type of a list type
+	NonNull bool     // Whether the type is marked with !
+}
+
+// IsList reports whether the type is a list type
+func (t *TypeRef) IsList() bool {
+	return t.OfType != nil
+}
+
+// String returns the type reference in GraphQL syntax
+func (t *TypeRef) String() string {
+	result := t.Name
+	if t.IsList() {
+		result = "[" + t.OfType.String() + "]"
+	}
+	if t.NonNull {
+		result += "!"
+	}
+	return result
+}
+
+// ParseType parses a standalone type reference, returning a *ParseError if the
+// input is not exactly one type
+func ParseType(input string) (typeRef *TypeRef, err error) {
+	defer recoverError(&err)
+	p := NewParser(input)
+	typeRef = p.parseType()
+	if p.curr.Type != lexer.TokenEOF {
+		p.fail("Unexpected token after type: %s", p.curr.Type)
+	}
+	return typeRef, nil
+}
+
+// parseType parses a named or list type reference with optional non-null markers
+func (p *Parser) parseType() *TypeRef {
+	typeRef := &TypeRef{}
+	if p.curr.Type == lexer.TokenBracketL {
+		p.enter()
+		p.eat(lexer.TokenBracketL)
+		typeRef.OfType = p.parseType()
+		p.eat(lexer.TokenBracketR)
+		p.leave()
+	} else {
+		typeRef.Name = p.curr.Value
+		p.eat(lexer.TokenIdent)
+	}
+	if p.curr.Type == lexer.TokenBang {
+		p.eat(lexer.TokenBang)
+		typeRef.NonNull = true
+	}
+	return typeRef
+}
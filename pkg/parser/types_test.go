@@ -0,0 +1,54 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+import (
+	"log"
+	"reflect"
+	"testing"
+)
+
+func TestParseType(t *testing.T) {
+	log.Println("Starting TestParseType")
+	tests := []struct {
+		input string
+		want  *TypeRef
+	}{
+		{input: "ID", want: &TypeRef{Name: "ID"}},
+		{input: "ID!", want: &TypeRef{Name: "ID", NonNull: true}},
+		{input: "[String]", want: &TypeRef{OfType: &TypeRef{Name: "String"}}},
+		{
+			input: "[String!]!",
+			want:  &TypeRef{OfType: &TypeRef{Name: "String", NonNull: true}, NonNull: true},
+		},
+		{
+			input: "[[Int]]",
+			want:  &TypeRef{OfType: &TypeRef{OfType: &TypeRef{Name: "Int"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseType(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected parse error: %s", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+			if got.String() != tt.input {
+				t.Errorf("Expected String() %s, got %s", tt.input, got.String())
+			}
+		})
+	}
+}
+
+func TestParseTypeErrors(t *testing.T) {
+	log.Println("Starting TestParseTypeErrors")
+	for _, input := range []string{"[ID", "ID!!", "[]", "!"} {
+		t.Run(input, func(t *testing.T) {
+			if _, err := ParseType(input); err == nil {
+				t.Errorf("Expected an error parsing %q", input)
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tom/graphqlinsights/pkg/lexer"
+)
+
+// ValueKind identifies the kind of an argument value
+type ValueKind string
+
+// Value kinds for GraphQL argument values
+const (
+	ValueString   ValueKind = "String"
+	ValueInt      ValueKind = "Int"
+	ValueFloat    ValueKind = "Float"
+	ValueBoolean  ValueKind = "Boolean"
+	ValueNull     ValueKind = "Null"
+	ValueEnum     ValueKind = "Enum"
+	ValueVariable ValueKind = "Variable"
+	ValueList     ValueKind = "List"
+	ValueObject   ValueKind = "Object"
+)
+
+// Value represents an argument value in a GraphQL query. It is implemented by
+// *StringValue, *IntValue, *FloatValue, *BoolValue, *NullValue, *EnumValue,
+// *VariableValue, *ListValue, and *ObjectValue.
+type Value interface {
+	Kind() ValueKind
+	String() string // The value in GraphQL syntax
+}
+
+// StringValue is a string literal, including block strings
+type StringValue struct {
+	Value string // Contents with escape sequences resolved
+}
+
+// IntValue is an integer literal
+type IntValue struct {
+	Raw string // Literal as written, so values out of range of int are kept intact
+}
+
+// FloatValue is a floating point literal
+type FloatValue struct {
+	Raw string // Literal as written, so its precision and notation are kept intact
+}
+
+// BoolValue is a true or false literal
+type BoolValue struct {
+	Value bool
+}
+
+// NullValue is the null literal
+type NullValue struct{}
+
+// EnumValue is an enum value such as ACTIVE
+type EnumValue struct {
+	Value string
+}
+
+// VariableValue is a reference to a variable such as $id
+type VariableValue struct {
+	Name string // Name without the $
+}
+
+// ListValue is a list value such as ["a", "b"]
+type ListValue struct {
+	Values []Value
+}
+
+// ObjectValue is an input object value such as { role: ADMIN }
+type ObjectValue struct {
+	Fields []*ObjectField // Fields in source order
+}
+
+// Argument represents a single name: value argument on a field or directive
+type Argument struct {
+	Name  string
+	Value Value
+}
+
+// ObjectField represents a single name: value pair inside an object value
+type ObjectField struct {
+	Name  string
+	Value Value
+}
+
+// Kind returns ValueString
+func (v *StringValue) Kind() ValueKind { return ValueString }
+
+// Kind returns ValueInt
+func (v *IntValue) Kind() ValueKind { return ValueInt }
+
+// Kind returns ValueFloat
+func (v *FloatValue) Kind() ValueKind { return ValueFloat }
+
+// Kind returns ValueBoolean
+func (v *BoolValue) Kind() ValueKind { return ValueBoolean }
+
+// Kind returns ValueNull
+func (v *NullValue) Kind() ValueKind { return ValueNull }
+
+// Kind returns ValueEnum
+func (v *EnumValue) Kind() ValueKind { return ValueEnum }
+
+// Kind returns ValueVariable
+func (v *VariableValue) Kind() ValueKind { return ValueVariable }
+
+// Kind returns ValueList
+func (v *ListValue) Kind() ValueKind { return ValueList }
+
+// Kind returns ValueObject
+func (v *ObjectValue) Kind() ValueKind { return ValueObject }
+
+// String returns the value as a quoted GraphQL string literal
+func (v *StringValue) String() string { return quoteString(v.Value) }
+
+// String returns the literal as written
+func (v *IntValue) String() string { return v.Raw }
+
+// String returns the literal as written
+func (v *FloatValue) String() string { return v.Raw }
+
+// String returns true or false
+func (v *BoolValue) String() string { return strconv.FormatBool(v.Value) }
+
+// String returns null
+func (v *NullValue) String() string { return "null" }
+
+// String returns the enum value's name
+func (v *EnumValue) String() string { return v.Value }
+
+// String returns the variable's name prefixed with $
+func (v *VariableValue) String() string { return "$" + v.Name }
+
+// String returns the items in brackets, separated by commas
+func (v *ListValue) String() string {
+	items := make([]string, len(v.Values))
+	for i, item := range v.Values {
+		items[i] = item.String()
+	}
+	return "[" + strings.Join(items, ", ") + "]"
+}
+
+// String returns the fields in braces, separated by commas
+func (v *ObjectValue) String() string {
+	fields := make([]string, len(v.Fields))
+	for i, field := range v.Fields {
+		fields[i] = field.Name + ": " + field.Value.String()
+	}
+	return "{" + strings.Join(fields, ", ") + "}"
+}
+
+// quoteString renders s as a GraphQL string literal
+func quoteString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&b, `\u%04x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// valuesEqual reports whether two values have the same kind and content
+func valuesEqual(a, b Value) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	switch a := a.(type) {
+	case *ListValue:
+		b, ok := b.(*ListValue)
+		if !ok || len(a.Values) != len(b.Values) {
+			return false
+		}
+		for i := range a.Values {
+			if !valuesEqual(a.Values[i], b.Values[i]) {
+				return false
+			}
+		}
+		return true
+	case *ObjectValue:
+		b, ok := b.(*ObjectValue)
+		if !ok || len(a.Fields) != len(b.Fields) {
+			return false
+		}
+		for i := range a.Fields {
+			if a.Fields[i].Name != b.Fields[i].Name || !valuesEqual(a.Fields[i].Value, b.Fields[i].Value) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a.Kind() == b.Kind() && a.String() == b.String()
+	}
+}
+
+// ParseValue parses a standalone GraphQL value such as a default value or a
+// variables literal. It returns a *ParseError if the input is not exactly one value.
+func ParseValue(input string) (value Value, err error) {
+	defer recoverError(&err)
+	p := NewParser(input)
+	value = p.parseValue()
+	if p.curr.Type != lexer.TokenEOF {
+		p.fail("Unexpected token after value: %s", p.curr.Type)
+	}
+	return value, nil
+}
+
+// parseValue parses an argument value: a string, int, float, boolean, null, enum,
+// variable, list, or input object. Field and directive arguments share this
+// parser so both accept the same recursive value grammar.
+func (p *Parser) parseValue() Value {
+	switch p.curr.Type {
+	case lexer.TokenString:
+		value := &StringValue{Value: p.curr.Value}
+		p.eat(lexer.TokenString)
+		return value
+	case lexer.TokenInt:
+		value := &IntValue{Raw: p.curr.Value}
+		p.eat(lexer.TokenInt)
+		return value
+	case lexer.TokenFloat:
+		value := &FloatValue{Raw: p.curr.Value}
+		p.eat(lexer.TokenFloat)
+		return value
+	case lexer.TokenDollar:
+		p.eat(lexer.TokenDollar)
+		name := p.curr.Value
+		p.eat(lexer.TokenIdent)
+		return &VariableValue{Name: name}
+	case lexer.TokenIdent:
+		var value Value
+		switch p.curr.Value {
+		case "true", "false":
+			value = &BoolValue{Value: p.curr.Value == "true"}
+		case "null":
+			value = &NullValue{}
+		default:
+			value = &EnumValue{Value: p.curr.Value}
+		}
+		p.eat(lexer.TokenIdent)
+		return value
+	case lexer.TokenBracketL:
+		return p.parseListValue()
+	case lexer.TokenBraceL:
+		return p.parseObjectValue()
+	}
+	p.fail("Unexpected token: expected a value but got %s", p.curr.Type)
+	return nil
+}
+
+// parseListValue parses a list value such as ["a", "b"]
+func (p *Parser) parseListValue() *ListValue {
+	p.enter()
+	defer p.leave()
+	p.eat(lexer.TokenBracketL)
+	value := &ListValue{}
+	for p.curr.Type != lexer.TokenBracketR {
+		value.Values = append(value.Values, p.parseValue())
+	}
+	p.eat(lexer.TokenBracketR)
+	return value
+}
+
+// parseObjectValue parses an input object value such as { role: ADMIN, ownerId: $uid }
+func (p *Parser) parseObjectValue() *ObjectValue {
+	p.enter()
+	defer p.leave()
+	p.eat(lexer.TokenBraceL)
+	value := &ObjectValue{}
+	for p.curr.Type == lexer.TokenIdent {
+		name := p.curr.Value
+		p.eat(lexer.TokenIdent)
+		p.eat(lexer.TokenColon)
+		value.Fields = append(value.Fields, &ObjectField{Name: name, Value: p.parseValue()})
+	}
+	p.eat(lexer.TokenBraceR)
+	return value
+}
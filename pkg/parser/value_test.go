@@ -0,0 +1,183 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+import (
+	"log"
+	"testing"
+)
+
+func TestParseValue(t *testing.T) {
+	log.Println("Starting TestParseValue")
+	tests := []struct {
+		name  string
+		input string
+		want  Value
+	}{
+		{name: "String", input: `"hello"`, want: &StringValue{Value: "hello"}},
+		{name: "Block string", input: `"""hello"""`, want: &StringValue{Value: "hello"}},
+		{name: "Int", input: `-42`, want: &IntValue{Raw: "-42"}},
+		{name: "Float", input: `6.022e23`, want: &FloatValue{Raw: "6.022e23"}},
+		{name: "Boolean", input: `true`, want: &BoolValue{Value: true}},
+		{name: "Null", input: `null`, want: &NullValue{}},
+		{name: "Enum", input: `ACTIVE`, want: &EnumValue{Value: "ACTIVE"}},
+		{name: "Variable", input: `$id`, want: &VariableValue{Name: "id"}},
+		{
+			name:  "List",
+			input: `["a", B, $c]`,
+			want: &ListValue{Values: []Value{
+				&StringValue{Value: "a"},
+				&EnumValue{Value: "B"},
+				&VariableValue{Name: "c"},
+			}},
+		},
+		{
+			name:  "Object",
+			input: `{ status: ACTIVE, tags: ["x"] }`,
+			want: &ObjectValue{Fields: []*ObjectField{
+				{Name: "status", Value: &EnumValue{Value: "ACTIVE"}},
+				{Name: "tags", Value: &ListValue{Values: []Value{&StringValue{Value: "x"}}}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseValue(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected parse error: %s", err)
+			}
+			if !valuesEqual(got, tt.want) {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseValueErrors(t *testing.T) {
+	log.Println("Starting TestParseValueErrors")
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "Trailing garbage", input: `"a" "b"`},
+		{name: "Trailing brace", input: `ACTIVE }`},
+		{name: "Unterminated list", input: `["a"`},
+		{name: "Empty input", input: ``},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseValue(tt.input); err == nil {
+				t.Errorf("Expected an error parsing %q", tt.input)
+			}
+		})
+	}
+}
+
+func TestValueString(t *testing.T) {
+	log.Println("Starting TestValueString")
+	value, err := ParseValue(`{ name: """say "hi" now""", tags: [A, $b] }`)
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+	if got, want := value.String(), `{name: "say \"hi\" now", tags: [A, $b]}`; got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestValueStringKinds(t *testing.T) {
+	log.Println("Starting TestValueStringKinds")
+	tests := []struct {
+		name  string
+		value Value
+		want  string
+	}{
+		{name: "String", value: &StringValue{Value: "hello"}, want: `"hello"`},
+		{name: "String with escapes", value: &StringValue{Value: "a \"b\" \\ c\n\t\x01"}, want: `"a \"b\" \\ c\n\t\u0001"`},
+		{name: "Empty string", value: &StringValue{}, want: `""`},
+		{name: "Int", value: &IntValue{Raw: "-42"}, want: `-42`},
+		{name: "Float", value: &FloatValue{Raw: "6.02e23"}, want: `6.02e23`},
+		{name: "Boolean", value: &BoolValue{Value: true}, want: `true`},
+		{name: "Null", value: &NullValue{}, want: `null`},
+		{name: "Enum", value: &EnumValue{Value: "DESC"}, want: `DESC`},
+		{name: "Variable", value: &VariableValue{Name: "id"}, want: `$id`},
+		{name: "Empty list", value: &ListValue{}, want: `[]`},
+		{
+			name: "List",
+			value: &ListValue{Values: []Value{
+				&IntValue{Raw: "1"},
+				&StringValue{Value: "two"},
+				&ListValue{Values: []Value{&NullValue{}}},
+			}},
+			want: `[1, "two", [null]]`,
+		},
+		{name: "Empty object", value: &ObjectValue{}, want: `{}`},
+		{
+			name: "Object",
+			value: &ObjectValue{Fields: []*ObjectField{
+				{Name: "b", Value: &VariableValue{Name: "b"}},
+				{Name: "a", Value: &ObjectValue{Fields: []*ObjectField{
+					{Name: "on", Value: &BoolValue{Value: false}},
+				}}},
+			}},
+			want: `{b: $b, a: {on: false}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.value.String()
+			if got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+			// The serialized form parses back to the same value
+			parsed, err := ParseValue(got)
+			if err != nil {
+				t.Fatalf("Could not parse %s: %s", got, err)
+			}
+			if !valuesEqual(parsed, tt.value) {
+				t.Errorf("Expected %s after re-parsing, got %s", tt.value, parsed)
+			}
+		})
+	}
+}
+
+func TestValuesEqual(t *testing.T) {
+	log.Println("Starting TestValuesEqual")
+	object := func(name string, value Value) Value {
+		return &ObjectValue{Fields: []*ObjectField{{Name: name, Value: value}}}
+	}
+	tests := []struct {
+		name string
+		a, b Value
+		want bool
+	}{
+		{name: "Both nil", want: true},
+		{name: "One nil", a: &NullValue{}, want: false},
+		{name: "Same scalar", a: &IntValue{Raw: "1"}, b: &IntValue{Raw: "1"}, want: true},
+		{name: "Same text, different kind", a: &EnumValue{Value: "true"}, b: &BoolValue{Value: true}, want: false},
+		{name: "Int and float", a: &IntValue{Raw: "1"}, b: &FloatValue{Raw: "1"}, want: false},
+		{
+			name: "Same list",
+			a:    &ListValue{Values: []Value{&EnumValue{Value: "A"}, &VariableValue{Name: "b"}}},
+			b:    &ListValue{Values: []Value{&EnumValue{Value: "A"}, &VariableValue{Name: "b"}}},
+			want: true,
+		},
+		{name: "Shorter list", a: &ListValue{Values: []Value{&NullValue{}}}, b: &ListValue{}, want: false},
+		{name: "Empty list and object", a: &ListValue{}, b: &ObjectValue{}, want: false},
+		{name: "Same object", a: object("a", &StringValue{Value: "x"}), b: object("a", &StringValue{Value: "x"}), want: true},
+		{name: "Different field name", a: object("a", &NullValue{}), b: object("b", &NullValue{}), want: false},
+		{name: "Different field value", a: object("a", &StringValue{Value: "x"}), b: object("a", &EnumValue{Value: "x"}), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := valuesEqual(tt.a, tt.b); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+			if got := valuesEqual(tt.b, tt.a); got != tt.want {
+				t.Errorf("Expected %v with the operands swapped, got %v", tt.want, got)
+			}
+		})
+	}
+}
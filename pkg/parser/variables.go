@@ -0,0 +1,91 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/tom/graphqlinsights/pkg/lexer"
+)
+
+// VariableDefinition represents a variable declared by an operation, such as $id: ID! = "1"
+type VariableDefinition struct {
+	Name         string // Variable name without the $
+	Type         *TypeRef
+	DefaultValue Value // Default value, or nil if none was given
+	Directives   []*Node
+}
+
+// String returns the variable definition in GraphQL syntax
+func (d *VariableDefinition) String() string {
+	s := "$" + d.Name + ": " + d.Type.String()
+	if d.DefaultValue != nil {
+		s += " = " + d.DefaultValue.String()
+	}
+	for _, directive := range d.Directives {
+		s += " " + directive.String()
+	}
+	return s
+}
+
+// parseVariableDefinitions parses an optional parenthesized list of variable definitions
+func (p *Parser) parseVariableDefinitions() []*VariableDefinition {
+	if p.curr.Type != lexer.TokenParenL {
+		return nil
+	}
+	p.eat(lexer.TokenParenL)
+	var definitions []*VariableDefinition
+	for p.curr.Type == lexer.TokenDollar {
+		definition := p.parseVariableDefinition()
+		for _, existing := range definitions {
+			if existing.Name == definition.Name {
+				p.fail("Duplicate variable $%s", definition.Name)
+			}
+		}
+		definitions = append(definitions, definition)
+	}
+	p.eat(lexer.TokenParenR)
+	return definitions
+}
+
+// parseVariableDefinition parses a single $name: Type = default @directive definition
+func (p *Parser) parseVariableDefinition() *VariableDefinition {
+	p.eat(lexer.TokenDollar)
+	definition := &VariableDefinition{Name: p.curr.Value}
+	p.eat(lexer.TokenIdent)
+	p.eat(lexer.TokenColon)
+	definition.Type = p.parseType()
+	if p.curr.Type == lexer.TokenEquals {
+		p.eat(lexer.TokenEquals)
+		definition.DefaultValue = p.parseValue()
+	}
+	definition.Directives = p.parseDirectives(LocationVariableDefinition)
+	return definition
+}
+
+// ReferencedVariables returns the sorted names of all variables referenced by
+// field and directive arguments anywhere in the tree rooted at n
+func (n *Node) ReferencedVariables() []string {
+	var names []string
+	Walk(n, func(node *Node) bool {
+		for _, arg := range node.Arguments {
+			names = appendVariables(names, arg.Value)
+		}
+		return true
+	})
+	sort.Strings(names)
+	return names
+}
+
+// UnusedVariables returns the names, in definition order, of variables the
+// operation defines but never references
+func (n *Node) UnusedVariables() []string {
+	referenced := n.ReferencedVariables()
+	var unused []string
+	for _, definition := range n.VariableDefinitions {
+		if !slices.Contains(referenced, definition.Name) {
+			unused = append(unused, definition.Name)
+		}
+	}
+	return unused
+}
@@ -0,0 +1,69 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+import (
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseVariableDefinitions(t *testing.T) {
+	log.Println("Starting TestParseVariableDefinitions")
+	query := mustParseQuery(t, `query Feed($id: ID!, $first: Int = 10, $tags: [String!] = ["go"] @deprecated) { feed(id: $id) { title } }`)
+
+	want := []string{`$id: ID!`, `$first: Int = 10`, `$tags: [String!] = ["go"] @deprecated`}
+	if len(query.VariableDefinitions) != len(want) {
+		t.Fatalf("Expected %d variable definitions, got %d", len(want), len(query.VariableDefinitions))
+	}
+	for i, definition := range query.VariableDefinitions {
+		if got := definition.String(); got != want[i] {
+			t.Errorf("Definition[%d]: expected %s, got %s", i, want[i], got)
+		}
+	}
+
+	tags := query.VariableDefinitions[2]
+	if !tags.Type.IsList() || tags.Type.OfType.Name != "String" || !tags.Type.OfType.NonNull {
+		t.Errorf("Expected $tags to be [String!], got %s", tags.Type)
+	}
+	if loc := tags.Directives[0].Location; loc != LocationVariableDefinition {
+		t.Errorf("Expected directive location %s, got %s", LocationVariableDefinition, loc)
+	}
+	if got, want := query.String(), `query Feed($id: ID!, $first: Int = 10, $tags: [String!] = ["go"] @deprecated) { feed(id: $id) { title } }`; got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestParseVariableDefinitionErrors(t *testing.T) {
+	log.Println("Starting TestParseVariableDefinitionErrors")
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "Missing type", input: `query Q($id) { a }`, want: "Unexpected token"},
+		{name: "Duplicate variable", input: `query Q($id: ID, $id: ID) { a }`, want: "Duplicate variable $id"},
+		{name: "Missing default", input: `query Q($id: ID =) { a }`, want: "expected a value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewParser(tt.input).ParseQuery()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestReferencedVariables(t *testing.T) {
+	log.Println("Starting TestReferencedVariables")
+	query := mustParseQuery(t, `query Q($a: ID, $b: Int) { user(id: $a) { name @include(if: $a) friends(filter: { team: $c }) { id } } }`)
+
+	if got, want := query.ReferencedVariables(), []string{"a", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected referenced %v, got %v", want, got)
+	}
+	if got, want := query.UnusedVariables(), []string{"b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected unused %v, got %v", want, got)
+	}
+}
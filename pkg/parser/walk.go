@@ -0,0 +1,18 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+// Walk traverses the AST rooted at n depth-first, calling visitor for each node
+// before its directives and then its selection set. If visitor returns false the
+// node's directives and selections are skipped. Argument values are not nodes;
+// visitors can inspect them through n.Arguments.
+func Walk(n *Node, visitor func(*Node) bool) {
+	if n == nil || !visitor(n) {
+		return
+	}
+	for _, directive := range n.Directives {
+		Walk(directive, visitor)
+	}
+	for _, child := range n.SelectionSet {
+		Walk(child, visitor)
+	}
+}
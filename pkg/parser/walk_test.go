@@ -0,0 +1,66 @@
+// Package parser provides parsing functionality for GraphQL queries
+package parser
+
+import (
+	"log"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	log.Println("Starting TestWalk")
+	input := `
+		query Feed @live {
+			viewer { id name }
+			posts(first: 10) @cache(ttl: 60) { title ...PostFields ... on Video @include(if: $v) { duration } }
+		}
+		fragment PostFields on Post { body }
+	`
+	document, err := NewParser(input).ParseDocument()
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+
+	counts := make(map[NodeType]int)
+	Walk(document, func(n *Node) bool {
+		counts[n.Type]++
+		return true
+	})
+
+	want := map[NodeType]int{
+		NodeDocument:           1,
+		NodeQuery:              1,
+		NodeFragmentDefinition: 1,
+		NodeField:              7,
+		NodeDirective:          3,
+		NodeFragmentSpread:     1,
+		NodeInlineFragment:     1,
+	}
+	for nodeType, count := range want {
+		if counts[nodeType] != count {
+			t.Errorf("Expected %d %s nodes, got %d", count, nodeType, counts[nodeType])
+		}
+	}
+}
+
+func TestWalkSkipsChildren(t *testing.T) {
+	log.Println("Starting TestWalkSkipsChildren")
+	query := mustParseQuery(t, `{ viewer { id name } posts { title } }`)
+
+	var visited []string
+	Walk(query, func(n *Node) bool {
+		if n.Type == NodeField {
+			visited = append(visited, n.Name)
+		}
+		return n.Name != "viewer"
+	})
+
+	want := []string{"viewer", "posts", "title"}
+	if len(visited) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, visited)
+	}
+	for i := range want {
+		if visited[i] != want[i] {
+			t.Errorf("Visited[%d]: expected %s, got %s", i, want[i], visited[i])
+		}
+	}
+}